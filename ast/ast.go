@@ -4,11 +4,16 @@ import (
 	"bytes"
 	"fmt"
 	"language/tokens"
+	"math/big"
+	"strconv"
+	"strings"
 )
 
 type Node interface {
 	fmt.Stringer
 	TokenLiteral() string
+	Pos() tokens.Position // position of the first character of the node
+	End() tokens.Position // position immediately after the node
 }
 
 type Statement interface {
@@ -42,6 +47,20 @@ func (p *Program) TokenLiteral() string {
 	return buf.String()
 }
 
+func (p *Program) Pos() tokens.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return tokens.Position{}
+}
+
+func (p *Program) End() tokens.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return tokens.Position{}
+}
+
 type LetStatement struct {
 	Token      tokens.Token
 	Identifier Identifier
@@ -54,6 +73,17 @@ func (a *LetStatement) TokenLiteral() string {
 	return a.Token.Literal
 }
 
+func (a *LetStatement) Pos() tokens.Position {
+	return a.Token.Pos
+}
+
+func (a *LetStatement) End() tokens.Position {
+	if a.Value != nil {
+		return a.Value.End()
+	}
+	return a.Identifier.End()
+}
+
 func (a *LetStatement) String() string {
 	var val string
 	if a.Value != nil {
@@ -79,13 +109,23 @@ func (r *ReturnStatement) TokenLiteral() string {
 	return r.Token.Literal
 }
 
-func (r *ReturnStatement) String() string {
-	var val string
+func (r *ReturnStatement) Pos() tokens.Position {
+	return r.Token.Pos
+}
+
+func (r *ReturnStatement) End() tokens.Position {
 	if r.Value != nil {
-		val = r.Value.String()
+		return r.Value.End()
+	}
+	return r.Token.End
+}
+
+func (r *ReturnStatement) String() string {
+	if r.Value == nil {
+		return fmt.Sprintf("%s;", r.Token.Literal)
 	}
 
-	return fmt.Sprintf("%s %s;", r.Token.Literal, val)
+	return fmt.Sprintf("%s %s;", r.Token.Literal, r.Value)
 }
 
 type Identifier struct {
@@ -99,13 +139,24 @@ func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *Identifier) Pos() tokens.Position {
+	return i.Token.Pos
+}
+
+func (i *Identifier) End() tokens.Position {
+	return i.Token.End
+}
+
 func (i *Identifier) String() string {
 	return i.Value
 }
 
+// IntegerLiteral keeps the literal as written in its token, e.g. 0xff or
+// 1_000, and prints it the same way.
 type IntegerLiteral struct {
 	Token tokens.Token
 	Value int64
+	Big   *big.Int // set instead of Value for literals out of the int64 range
 }
 
 func (i *IntegerLiteral) expressionNode() {}
@@ -114,10 +165,64 @@ func (i *IntegerLiteral) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *IntegerLiteral) Pos() tokens.Position {
+	return i.Token.Pos
+}
+
+func (i *IntegerLiteral) End() tokens.Position {
+	return i.Token.End
+}
+
 func (i *IntegerLiteral) String() string {
 	return i.TokenLiteral()
 }
 
+type FloatLiteral struct {
+	Token tokens.Token
+	Value float64
+}
+
+func (f *FloatLiteral) expressionNode() {}
+
+func (f *FloatLiteral) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (f *FloatLiteral) Pos() tokens.Position {
+	return f.Token.Pos
+}
+
+func (f *FloatLiteral) End() tokens.Position {
+	return f.Token.End
+}
+
+func (f *FloatLiteral) String() string {
+	return f.TokenLiteral()
+}
+
+type StringLiteral struct {
+	Token tokens.Token
+	Value string
+}
+
+func (s *StringLiteral) expressionNode() {}
+
+func (s *StringLiteral) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *StringLiteral) Pos() tokens.Position {
+	return s.Token.Pos
+}
+
+func (s *StringLiteral) End() tokens.Position {
+	return s.Token.End
+}
+
+func (s *StringLiteral) String() string {
+	return strconv.Quote(s.Value)
+}
+
 type BooleanLiteral struct {
 	Token tokens.Token
 	Value bool
@@ -129,6 +234,14 @@ func (b *BooleanLiteral) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b *BooleanLiteral) Pos() tokens.Position {
+	return b.Token.Pos
+}
+
+func (b *BooleanLiteral) End() tokens.Position {
+	return b.Token.End
+}
+
 func (b *BooleanLiteral) String() string {
 	return b.TokenLiteral()
 }
@@ -144,6 +257,17 @@ func (e *ExpressionStatement) TokenLiteral() string {
 	return e.Token.Literal
 }
 
+func (e *ExpressionStatement) Pos() tokens.Position {
+	return e.Token.Pos
+}
+
+func (e *ExpressionStatement) End() tokens.Position {
+	if e.Expression != nil {
+		return e.Expression.End()
+	}
+	return e.Token.End
+}
+
 func (e *ExpressionStatement) String() string {
 	if e.Expression != nil {
 		return e.Expression.String()
@@ -163,6 +287,17 @@ func (p *PrefixExpression) TokenLiteral() string {
 	return p.Token.Literal
 }
 
+func (p *PrefixExpression) Pos() tokens.Position {
+	return p.Token.Pos
+}
+
+func (p *PrefixExpression) End() tokens.Position {
+	if p.Right != nil {
+		return p.Right.End()
+	}
+	return p.Token.End
+}
+
 func (p *PrefixExpression) String() string {
 	return fmt.Sprintf("(%s%s)", p.Operator, p.Right)
 }
@@ -180,6 +315,287 @@ func (i *InfixExpression) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *InfixExpression) Pos() tokens.Position {
+	if i.Left != nil {
+		return i.Left.Pos()
+	}
+	return i.Token.Pos
+}
+
+func (i *InfixExpression) End() tokens.Position {
+	if i.Right != nil {
+		return i.Right.End()
+	}
+	return i.Token.End
+}
+
 func (i *InfixExpression) String() string {
 	return fmt.Sprintf("(%s %s %s)", i.Left, i.Operator, i.Right)
 }
+
+// AssignExpression rebinds a variable, or replaces an element of the
+// collection held by a variable when the target is an index expression.
+type AssignExpression struct {
+	Token    tokens.Token // = or a compound assignment operator such as +=
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (a *AssignExpression) expressionNode() {}
+
+func (a *AssignExpression) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+func (a *AssignExpression) Pos() tokens.Position {
+	if a.Target != nil {
+		return a.Target.Pos()
+	}
+	return a.Token.Pos
+}
+
+func (a *AssignExpression) End() tokens.Position {
+	if a.Value != nil {
+		return a.Value.End()
+	}
+	return a.Token.End
+}
+
+func (a *AssignExpression) String() string {
+	return fmt.Sprintf("(%s %s %s)", a.Target, a.Operator, a.Value)
+}
+
+// InfixOperator returns the operator a compound assignment applies to the
+// current value and the assigned value, or "" for a plain assignment.
+func (a *AssignExpression) InfixOperator() string {
+	return strings.TrimSuffix(a.Operator, "=")
+}
+
+// Variable returns the variable rebound by the assignment and the indices
+// leading from its value to the assigned element, the variable is nil if
+// the target can't be assigned to.
+func (a *AssignExpression) Variable() (*Identifier, []Expression) {
+	var indices []Expression
+
+	target := a.Target
+	for {
+		switch t := target.(type) {
+		case *Identifier:
+			return t, indices
+		case *IndexExpression:
+			indices = append([]Expression{t.Index}, indices...)
+			target = t.Left
+		default:
+			return nil, nil
+		}
+	}
+}
+
+type BlockStatement struct {
+	Token      tokens.Token // {
+	Statements []Statement
+	EndToken   tokens.Token // }
+}
+
+func (b *BlockStatement) statementNode() {}
+
+func (b *BlockStatement) TokenLiteral() string {
+	return b.Token.Literal
+}
+
+func (b *BlockStatement) Pos() tokens.Position {
+	return b.Token.Pos
+}
+
+func (b *BlockStatement) End() tokens.Position {
+	return b.EndToken.End
+}
+
+func (b *BlockStatement) String() string {
+	statements := make([]string, len(b.Statements))
+	for i, s := range b.Statements {
+		statements[i] = s.String()
+	}
+
+	return fmt.Sprintf("{ %s }", strings.Join(statements, " "))
+}
+
+type FunctionLiteral struct {
+	Token      tokens.Token // fun
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (f *FunctionLiteral) expressionNode() {}
+
+func (f *FunctionLiteral) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (f *FunctionLiteral) Pos() tokens.Position {
+	return f.Token.Pos
+}
+
+func (f *FunctionLiteral) End() tokens.Position {
+	return f.Body.End()
+}
+
+func (f *FunctionLiteral) String() string {
+	params := make([]string, len(f.Parameters))
+	for i, p := range f.Parameters {
+		params[i] = p.String()
+	}
+
+	return fmt.Sprintf("%s(%s) %s", f.Token.Literal, strings.Join(params, ", "), f.Body)
+}
+
+type CallExpression struct {
+	Token     tokens.Token // (
+	Function  Expression
+	Arguments []Expression
+	EndToken  tokens.Token // )
+}
+
+func (c *CallExpression) expressionNode() {}
+
+func (c *CallExpression) TokenLiteral() string {
+	return c.Token.Literal
+}
+
+func (c *CallExpression) Pos() tokens.Position {
+	return c.Function.Pos()
+}
+
+func (c *CallExpression) End() tokens.Position {
+	return c.EndToken.End
+}
+
+func (c *CallExpression) String() string {
+	args := make([]string, len(c.Arguments))
+	for i, a := range c.Arguments {
+		args[i] = a.String()
+	}
+
+	return fmt.Sprintf("%s(%s)", c.Function, strings.Join(args, ", "))
+}
+
+type IfExpression struct {
+	Token       tokens.Token // if
+	Condition   Expression
+	Consequence *BlockStatement
+	Alternative *BlockStatement
+}
+
+func (i *IfExpression) expressionNode() {}
+
+func (i *IfExpression) TokenLiteral() string {
+	return i.Token.Literal
+}
+
+func (i *IfExpression) Pos() tokens.Position {
+	return i.Token.Pos
+}
+
+func (i *IfExpression) End() tokens.Position {
+	if i.Alternative != nil {
+		return i.Alternative.End()
+	}
+	return i.Consequence.End()
+}
+
+func (i *IfExpression) String() string {
+	if i.Alternative != nil {
+		return fmt.Sprintf("%s %s %s else %s", i.Token.Literal, i.Condition, i.Consequence, i.Alternative)
+	}
+
+	return fmt.Sprintf("%s %s %s", i.Token.Literal, i.Condition, i.Consequence)
+}
+
+type ArrayLiteral struct {
+	Token    tokens.Token // [
+	Elements []Expression
+	EndToken tokens.Token // ]
+}
+
+func (a *ArrayLiteral) expressionNode() {}
+
+func (a *ArrayLiteral) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+func (a *ArrayLiteral) Pos() tokens.Position {
+	return a.Token.Pos
+}
+
+func (a *ArrayLiteral) End() tokens.Position {
+	return a.EndToken.End
+}
+
+func (a *ArrayLiteral) String() string {
+	elements := make([]string, len(a.Elements))
+	for i, e := range a.Elements {
+		elements[i] = e.String()
+	}
+
+	return fmt.Sprintf("[%s]", strings.Join(elements, ", "))
+}
+
+type IndexExpression struct {
+	Token    tokens.Token // [
+	Left     Expression
+	Index    Expression
+	EndToken tokens.Token // ]
+}
+
+func (i *IndexExpression) expressionNode() {}
+
+func (i *IndexExpression) TokenLiteral() string {
+	return i.Token.Literal
+}
+
+func (i *IndexExpression) Pos() tokens.Position {
+	return i.Left.Pos()
+}
+
+func (i *IndexExpression) End() tokens.Position {
+	return i.EndToken.End
+}
+
+func (i *IndexExpression) String() string {
+	return fmt.Sprintf("(%s[%s])", i.Left, i.Index)
+}
+
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+type HashLiteral struct {
+	Token    tokens.Token // {
+	Pairs    []HashPair   // in source order
+	EndToken tokens.Token // }
+}
+
+func (h *HashLiteral) expressionNode() {}
+
+func (h *HashLiteral) TokenLiteral() string {
+	return h.Token.Literal
+}
+
+func (h *HashLiteral) Pos() tokens.Position {
+	return h.Token.Pos
+}
+
+func (h *HashLiteral) End() tokens.Position {
+	return h.EndToken.End
+}
+
+func (h *HashLiteral) String() string {
+	pairs := make([]string, len(h.Pairs))
+	for i, p := range h.Pairs {
+		pairs[i] = fmt.Sprintf("%s: %s", p.Key, p.Value)
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
+}
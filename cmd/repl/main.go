@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"language/repl"
+	"os"
+)
+
+func main() {
+	bigIntegers := flag.Bool("big-integers", false, "use arbitrary-precision integers instead of wrapping around on overflow")
+	flag.Parse()
+
+	var opts []repl.Option
+	if *bigIntegers {
+		opts = append(opts, repl.WithBigIntegers())
+	}
+
+	fmt.Println("language repl, type :help for a list of commands")
+	repl.Start(os.Stdin, os.Stdout, opts...)
+}
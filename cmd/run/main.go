@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"language/ast"
+	"language/compiler"
+	"language/diagnostics"
+	"language/evaluator"
+	"language/lexer"
+	"language/object"
+	"language/parser"
+	"language/tokens"
+	"language/vm"
+	"os"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1 // the program failed to parse or evaluate
+	exitUsage = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: run [flags] [file]")
+		fmt.Fprintln(stderr, "executes the file, or the standard input when the file is - or missing")
+		flags.PrintDefaults()
+	}
+
+	dumpTokens := flags.Bool("dump-tokens", false, "print the tokens produced by the lexer instead of executing")
+	dumpAST := flags.Bool("dump-ast", false, "print the parsed program instead of executing")
+	dumpBytecode := flags.Bool("dump-bytecode", false, "print the compiled bytecode instead of executing")
+	useVM := flags.Bool("vm", false, "execute the compiled bytecode in the virtual machine instead of the evaluator")
+	bigIntegers := flags.Bool("big-integers", false, "use arbitrary-precision integers instead of wrapping around on overflow")
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	name, source, err := readSource(flags.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	if *dumpTokens {
+		printTokens(stdout, lexer.New(source, lexer.WithFile(name), lexer.WithComments()))
+		return exitOK
+	}
+
+	var opts []parser.Option
+	if *bigIntegers {
+		opts = append(opts, parser.WithBigIntegers())
+	}
+
+	p := parser.New(lexer.New(source, lexer.WithFile(name)), opts...)
+	program, err := p.Parse()
+	if err != nil {
+		for _, err := range p.Errors() {
+			fmt.Fprintln(stderr, diagnostics.Format(source, err.Pos(), err.Error()))
+		}
+		return exitError
+	}
+
+	if *dumpAST {
+		printProgram(stdout, program)
+		return exitOK
+	}
+
+	rt := &object.Runtime{Stdout: stdout, BigIntegers: *bigIntegers}
+
+	if *useVM || *dumpBytecode {
+		c := compiler.New()
+		if err := c.Compile(program); err != nil {
+			printError(stderr, source, err)
+			return exitError
+		}
+
+		if *dumpBytecode {
+			fmt.Fprint(stdout, compiler.Disassemble(c.Bytecode()))
+			return exitOK
+		}
+
+		if err := vm.New(c.Bytecode(), vm.WithRuntime(rt)).Run(); err != nil {
+			printError(stderr, source, err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	result := evaluator.Eval(program, object.NewEnvironmentWithRuntime(rt))
+	if err, ok := result.(*object.Error); ok {
+		fmt.Fprintln(stderr, diagnostics.Format(source, err.Pos, err.Message))
+		return exitError
+	}
+
+	return exitOK
+}
+
+func readSource(path string, stdin io.Reader) (string, string, error) {
+	if path == "" || path == "-" {
+		source, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", "", fmt.Errorf("reading standard input failed: %w", err)
+		}
+		return "<stdin>", string(source), nil
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("reading file failed: %w", err)
+	}
+	return path, string(source), nil
+}
+
+// printError prints compile and runtime errors of the virtual machine with
+// the source line they occurred at.
+func printError(out io.Writer, source string, err error) {
+	var compileErr *compiler.Error
+	var runtimeErr *vm.Error
+
+	switch {
+	case errors.As(err, &compileErr):
+		fmt.Fprintln(out, diagnostics.Format(source, compileErr.Pos, compileErr.Message))
+	case errors.As(err, &runtimeErr):
+		fmt.Fprintln(out, diagnostics.Format(source, runtimeErr.Pos, runtimeErr.Message))
+	default:
+		fmt.Fprintln(out, err)
+	}
+}
+
+func printTokens(out io.Writer, l *lexer.Lexer) {
+	for token := l.NextToken(); token.Type != tokens.EOF; token = l.NextToken() {
+		fmt.Fprintf(out, "%-16s %-10s %q\n", token.Pos, token.Type, token.Literal)
+	}
+}
+
+func printProgram(out io.Writer, program *ast.Program) {
+	for _, st := range program.Statements {
+		fmt.Fprintln(out, st)
+	}
+}
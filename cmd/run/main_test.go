@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runWith(args []string, stdin string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func Test_RunFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.lang")
+	source := "let add = fun(a, b) { a + b };\nputs(add(1, 2));\n"
+	require.NoError(t, os.WriteFile(path, []byte(source), 0o644))
+
+	code, stdout, stderr := runWith([]string{path}, "")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "3\n", stdout)
+	assert.Equal(t, "", stderr)
+}
+
+func Test_RunVM(t *testing.T) {
+	code, stdout, stderr := runWith([]string{"--vm"}, "let add = fun(a, b) { a + b };\nputs(add(1, 2));\n")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "3\n", stdout)
+	assert.Equal(t, "", stderr)
+
+	code, _, stderr = runWith([]string{"--vm"}, "puts(1);\nfoo + 1;")
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, "<stdin>:2:1: identifier not found: foo\n    foo + 1;\n    ^\n", stderr)
+
+	code, _, stderr = runWith([]string{"--vm"}, "let f = fun(x) { x / 0 };\nf(1);")
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, "<stdin>:1:18: division by zero: 1 / 0\n    let f = fun(x) { x / 0 };\n                     ^\n", stderr)
+}
+
+func Test_RunBigIntegers(t *testing.T) {
+	source := "puts(9223372036854775807 + 1, 18446744073709551616)"
+
+	code, stdout, _ := runWith([]string{"--big-integers"}, source)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "9223372036854775808\n18446744073709551616\n", stdout)
+
+	code, _, stderr := runWith(nil, source)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "<stdin>:1:31: integer literal 18446744073709551616 out of range")
+}
+
+func Test_RunStdin(t *testing.T) {
+	for _, args := range [][]string{{}, {"-"}} {
+		code, stdout, _ := runWith(args, `puts("hi");`)
+		assert.Equal(t, exitOK, code)
+		assert.Equal(t, "hi\n", stdout)
+	}
+}
+
+func Test_RunErrors(t *testing.T) {
+	tests := []struct {
+		args   []string
+		stdin  string
+		code   int
+		stderr string
+	}{
+		{
+			stdin:  "let x 5;\nlet = 2;",
+			code:   exitError,
+			stderr: "<stdin>:1:7: parsing let statement failed: expected =, got INT\n    let x 5;\n          ^\n<stdin>:2:5: parsing let statement failed: expected IDENTIFIER, got =\n    let = 2;\n        ^\n",
+		},
+		{
+			stdin:  "puts(1);\nfoo + 1;",
+			code:   exitError,
+			stderr: "<stdin>:2:1: identifier not found: foo\n    foo + 1;\n    ^\n",
+		},
+		{
+			stdin:  "let f = fun() { f() };\nf();",
+			code:   exitError,
+			stderr: "<stdin>:1:17: stack overflow\n    let f = fun() { f() };\n                    ^\n",
+		},
+		{
+			args:   []string{"does-not-exist.lang"},
+			code:   exitUsage,
+			stderr: "reading file failed: open does-not-exist.lang: no such file or directory\n",
+		},
+		{
+			args: []string{"a", "b"},
+			code: exitUsage,
+		},
+		{
+			args: []string{"--unknown"},
+			code: exitUsage,
+		},
+	}
+
+	for _, test := range tests {
+		code, _, stderr := runWith(test.args, test.stdin)
+		assert.Equal(t, test.code, code)
+		if test.stderr != "" {
+			assert.Equal(t, test.stderr, stderr)
+		}
+	}
+}
+
+func Test_Dump(t *testing.T) {
+	code, stdout, _ := runWith([]string{"--dump-tokens"}, "let x = 1;")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(
+		t,
+		"<stdin>:1:1      LET        \"let\"\n"+
+			"<stdin>:1:5      IDENTIFIER \"x\"\n"+
+			"<stdin>:1:7      =          \"=\"\n"+
+			"<stdin>:1:9      INT        \"1\"\n"+
+			"<stdin>:1:10     ;          \";\"\n",
+		stdout,
+	)
+
+	code, stdout, _ = runWith([]string{"--dump-ast"}, "let x = 1 + 2 * 3; puts(x)")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "let x = (1 + (2 * 3));\nputs(x)\n", stdout)
+
+	code, stdout, _ = runWith([]string{"--dump-bytecode"}, "let x = 1; puts(x + 2)")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(
+		t,
+		"0000 OpConstant 0\n"+
+			"0003 OpSetGlobal 0\n"+
+			"0006 OpGetBuiltin 0\n"+
+			"0008 OpGetGlobal 0\n"+
+			"0011 OpConstant 1\n"+
+			"0014 OpAdd\n"+
+			"0015 OpCall 1\n"+
+			"0017 OpPop\n"+
+			"\n"+
+			"constants:\n"+
+			"0000 INTEGER 1\n"+
+			"0001 INTEGER 2\n",
+		stdout,
+	)
+}
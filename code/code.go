@@ -0,0 +1,223 @@
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"language/tokens"
+)
+
+type Instructions []byte
+
+// SourceMap maps instruction offsets to the source position they were
+// compiled from, only instructions that can fail at runtime are recorded.
+type SourceMap map[int]tokens.Position
+
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota
+	OpPop
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpPow
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShiftLeft
+	OpShiftRight
+	OpTrue
+	OpFalse
+	OpNull
+	OpEqual
+	OpNotEqual
+	OpLessThan
+	OpGreaterThan
+	OpLessEqual
+	OpGreaterEqual
+	OpMinus
+	OpBang
+	OpBitNot
+	OpJumpNotTruthy
+	OpJump
+	OpGetGlobal
+	OpSetGlobal
+	OpGetLocal
+	OpSetLocal
+	OpGetBuiltin
+	OpGetFree
+	OpSetFree
+	OpCaptureLocal
+	OpCaptureFree
+	OpArray
+	OpHash
+	OpHashKey
+	OpIndex
+	OpSetIndex
+	OpUpdateIndex
+	OpCall
+	OpReturnValue
+	OpReturn
+	OpClosure
+)
+
+type Definition struct {
+	Name          string
+	OperandWidths []int // width in bytes of each operand
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:      {Name: "OpConstant", OperandWidths: []int{2}}, // constant index
+	OpPop:           {Name: "OpPop", OperandWidths: []int{}},
+	OpAdd:           {Name: "OpAdd", OperandWidths: []int{}},
+	OpSub:           {Name: "OpSub", OperandWidths: []int{}},
+	OpMul:           {Name: "OpMul", OperandWidths: []int{}},
+	OpDiv:           {Name: "OpDiv", OperandWidths: []int{}},
+	OpMod:           {Name: "OpMod", OperandWidths: []int{}},
+	OpPow:           {Name: "OpPow", OperandWidths: []int{}},
+	OpBitAnd:        {Name: "OpBitAnd", OperandWidths: []int{}},
+	OpBitOr:         {Name: "OpBitOr", OperandWidths: []int{}},
+	OpBitXor:        {Name: "OpBitXor", OperandWidths: []int{}},
+	OpShiftLeft:     {Name: "OpShiftLeft", OperandWidths: []int{}},
+	OpShiftRight:    {Name: "OpShiftRight", OperandWidths: []int{}},
+	OpTrue:          {Name: "OpTrue", OperandWidths: []int{}},
+	OpFalse:         {Name: "OpFalse", OperandWidths: []int{}},
+	OpNull:          {Name: "OpNull", OperandWidths: []int{}},
+	OpEqual:         {Name: "OpEqual", OperandWidths: []int{}},
+	OpNotEqual:      {Name: "OpNotEqual", OperandWidths: []int{}},
+	OpLessThan:      {Name: "OpLessThan", OperandWidths: []int{}},
+	OpGreaterThan:   {Name: "OpGreaterThan", OperandWidths: []int{}},
+	OpLessEqual:     {Name: "OpLessEqual", OperandWidths: []int{}},
+	OpGreaterEqual:  {Name: "OpGreaterEqual", OperandWidths: []int{}},
+	OpMinus:         {Name: "OpMinus", OperandWidths: []int{}},
+	OpBang:          {Name: "OpBang", OperandWidths: []int{}},
+	OpBitNot:        {Name: "OpBitNot", OperandWidths: []int{}},
+	OpJumpNotTruthy: {Name: "OpJumpNotTruthy", OperandWidths: []int{2}}, // jump offset
+	OpJump:          {Name: "OpJump", OperandWidths: []int{2}},          // jump offset
+	OpGetGlobal:     {Name: "OpGetGlobal", OperandWidths: []int{2}},     // global index
+	OpSetGlobal:     {Name: "OpSetGlobal", OperandWidths: []int{2}},     // global index
+	OpGetLocal:      {Name: "OpGetLocal", OperandWidths: []int{1}},      // local index
+	OpSetLocal:      {Name: "OpSetLocal", OperandWidths: []int{1}},      // local index
+	OpGetBuiltin:    {Name: "OpGetBuiltin", OperandWidths: []int{1}},    // builtin index
+	OpGetFree:       {Name: "OpGetFree", OperandWidths: []int{1}},       // free variable index
+	OpSetFree:       {Name: "OpSetFree", OperandWidths: []int{1}},       // free variable index
+	OpCaptureLocal:  {Name: "OpCaptureLocal", OperandWidths: []int{1}},  // local index
+	OpCaptureFree:   {Name: "OpCaptureFree", OperandWidths: []int{1}},   // free variable index
+	OpArray:         {Name: "OpArray", OperandWidths: []int{2}},         // number of elements
+	OpHash:          {Name: "OpHash", OperandWidths: []int{2}},          // number of keys and values
+	OpHashKey:       {Name: "OpHashKey", OperandWidths: []int{}},
+	OpIndex:         {Name: "OpIndex", OperandWidths: []int{}},
+	OpSetIndex:      {Name: "OpSetIndex", OperandWidths: []int{1}},       // number of indices
+	OpUpdateIndex:   {Name: "OpUpdateIndex", OperandWidths: []int{1, 1}}, // number of indices, operator opcode
+	OpCall:          {Name: "OpCall", OperandWidths: []int{1}},           // number of arguments
+	OpReturnValue:   {Name: "OpReturnValue", OperandWidths: []int{}},
+	OpReturn:        {Name: "OpReturn", OperandWidths: []int{}},
+	OpClosure:       {Name: "OpClosure", OperandWidths: []int{2, 1}}, // constant index, number of free variables
+}
+
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return def, nil
+}
+
+// Make encodes the opcode and its operands, operands are big-endian.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	length := 1
+	for _, w := range def.OperandWidths {
+		length += w
+	}
+
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		switch def.OperandWidths[i] {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += def.OperandWidths[i]
+	}
+
+	return instruction
+}
+
+// ReadOperands decodes the operands following an opcode and returns them
+// with the number of bytes read.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+func ReadUint8(ins Instructions) uint8 {
+	return ins[0]
+}
+
+// String disassembles the instructions, one instruction per line prefixed
+// by its offset.
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&out, "%04d %s\n", i, ins.formatInstruction(def, operands))
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
+
+func (ins Instructions) formatInstruction(def *Definition, operands []int) string {
+	if len(operands) != len(def.OperandWidths) {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n", len(operands), len(def.OperandWidths))
+	}
+
+	switch len(operands) {
+	case 0:
+		return def.Name
+	case 1:
+		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	}
+
+	return fmt.Sprintf("ERROR: unhandled operand count for %s\n", def.Name)
+}
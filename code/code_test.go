@@ -0,0 +1,75 @@
+package code
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_Make(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{op: OpConstant, operands: []int{65534}, expected: []byte{byte(OpConstant), 255, 254}},
+		{op: OpAdd, operands: []int{}, expected: []byte{byte(OpAdd)}},
+		{op: OpGetLocal, operands: []int{255}, expected: []byte{byte(OpGetLocal), 255}},
+		{op: OpClosure, operands: []int{65534, 255}, expected: []byte{byte(OpClosure), 255, 254, 255}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, Make(test.op, test.operands...))
+	}
+}
+
+func Test_ReadOperands(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		read     int
+	}{
+		{op: OpConstant, operands: []int{65535}, read: 2},
+		{op: OpGetLocal, operands: []int{255}, read: 1},
+		{op: OpClosure, operands: []int{65535, 255}, read: 3},
+	}
+
+	for _, test := range tests {
+		instruction := Make(test.op, test.operands...)
+
+		def, err := Lookup(byte(test.op))
+		require.NoError(t, err)
+
+		operands, read := ReadOperands(def, instruction[1:])
+		assert.Equal(t, test.read, read)
+		assert.Equal(t, test.operands, operands)
+	}
+}
+
+func Test_InstructionsString(t *testing.T) {
+	instructions := []Instructions{
+		Make(OpAdd),
+		Make(OpGetLocal, 1),
+		Make(OpConstant, 2),
+		Make(OpConstant, 65535),
+		Make(OpClosure, 65535, 255),
+	}
+
+	var concatted Instructions
+	for _, ins := range instructions {
+		concatted = append(concatted, ins...)
+	}
+
+	expected := `0000 OpAdd
+0001 OpGetLocal 1
+0003 OpConstant 2
+0006 OpConstant 65535
+0009 OpClosure 65535 255
+`
+	assert.Equal(t, expected, concatted.String())
+}
+
+func Test_Lookup(t *testing.T) {
+	_, err := Lookup(255)
+	assert.EqualError(t, err, "opcode 255 undefined")
+}
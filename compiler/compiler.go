@@ -0,0 +1,631 @@
+package compiler
+
+import (
+	"fmt"
+	"language/ast"
+	"language/code"
+	"language/object"
+	"language/tokens"
+	"math"
+)
+
+// Error is returned when the program can't be compiled, for example when
+// it refers to a name that is never defined.
+type Error struct {
+	Pos     tokens.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type Bytecode struct {
+	Instructions code.Instructions
+	Positions    code.SourceMap
+	Constants    []object.Object
+}
+
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+// CompilationScope holds the instructions of the function being compiled,
+// the main program is compiled in the outermost scope.
+type CompilationScope struct {
+	instructions        code.Instructions
+	positions           code.SourceMap
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+}
+
+type Compiler struct {
+	constants   []object.Object
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+}
+
+func New() *Compiler {
+	symbolTable := NewSymbolTable()
+	for i, b := range object.Builtins {
+		symbolTable.DefineBuiltin(i, b.Name)
+	}
+
+	return NewWithState(symbolTable, []object.Object{})
+}
+
+// NewWithState creates a compiler that continues with the symbols and
+// constants of a previous compilation, which is used by interactive sessions.
+func NewWithState(symbolTable *SymbolTable, constants []object.Object) *Compiler {
+	return &Compiler{
+		constants:   constants,
+		symbolTable: symbolTable,
+		scopes:      []CompilationScope{{positions: code.SourceMap{}}},
+	}
+}
+
+func (c *Compiler) Compile(node ast.Node) error {
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, st := range node.Statements {
+			if err := c.Compile(st); err != nil {
+				return err
+			}
+		}
+	case *ast.ExpressionStatement:
+		if err := c.Compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+	case *ast.BlockStatement:
+		for _, st := range node.Statements {
+			if err := c.Compile(st); err != nil {
+				return err
+			}
+		}
+	case *ast.LetStatement:
+		return c.compileLetStatement(node)
+	case *ast.ReturnStatement:
+		if node.Value == nil {
+			c.emit(code.OpReturn)
+			return nil
+		}
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+	case *ast.PrefixExpression:
+		return c.compilePrefixExpression(node)
+	case *ast.InfixExpression:
+		return c.compileInfixExpression(node)
+	case *ast.AssignExpression:
+		return c.compileAssignExpression(node)
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			return newError(node, "identifier not found: %s", node.Value)
+		}
+		c.loadSymbol(symbol)
+	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return c.emitConstant(node, &object.BigInteger{Value: node.Big})
+		}
+		return c.emitConstant(node, &object.Integer{Value: node.Value})
+	case *ast.FloatLiteral:
+		return c.emitConstant(node, &object.Float{Value: node.Value})
+	case *ast.StringLiteral:
+		return c.emitConstant(node, &object.String{Value: node.Value})
+	case *ast.BooleanLiteral:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+	case *ast.ArrayLiteral:
+		for _, e := range node.Elements {
+			if err := c.Compile(e); err != nil {
+				return err
+			}
+		}
+		if len(node.Elements) > math.MaxUint16 {
+			return newError(node, "too many array elements")
+		}
+		c.emit(code.OpArray, len(node.Elements))
+	case *ast.HashLiteral:
+		// keys and values are interleaved in source order and every key is
+		// checked before its value is evaluated, like in the evaluator
+		for _, pair := range node.Pairs {
+			if err := c.Compile(pair.Key); err != nil {
+				return err
+			}
+			c.emitAt(pair.Key, code.OpHashKey)
+			if err := c.Compile(pair.Value); err != nil {
+				return err
+			}
+		}
+		if len(node.Pairs)*2 > math.MaxUint16 {
+			return newError(node, "too many hash pairs")
+		}
+		c.emit(code.OpHash, len(node.Pairs)*2)
+	case *ast.IndexExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Index); err != nil {
+			return err
+		}
+		c.emitAt(node, code.OpIndex)
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral(node, "")
+	case *ast.CallExpression:
+		if err := c.Compile(node.Function); err != nil {
+			return err
+		}
+		for _, arg := range node.Arguments {
+			if err := c.Compile(arg); err != nil {
+				return err
+			}
+		}
+		if len(node.Arguments) > math.MaxUint8 {
+			return newError(node, "too many arguments: %d", len(node.Arguments))
+		}
+		c.emitAt(node, code.OpCall, len(node.Arguments))
+	default:
+		return newError(node, "unknown node %T", node)
+	}
+
+	return nil
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Positions:    c.scopes[c.scopeIndex].positions,
+		Constants:    c.constants,
+	}
+}
+
+// SymbolTable returns the global symbols, to be passed to NewWithState.
+func (c *Compiler) SymbolTable() *SymbolTable {
+	return c.symbolTable
+}
+
+/*
+	Statement compilation
+*/
+
+func (c *Compiler) compileLetStatement(node *ast.LetStatement) error {
+	// function names are defined before the body is compiled, so functions
+	// can call themselves, for other values the name is defined
+	// after, so the value can refer to the previous binding of the name
+	fn, isFunction := node.Value.(*ast.FunctionLiteral)
+
+	var symbol Symbol
+	if isFunction {
+		previous, bound := c.symbolTable.store[node.Identifier.Value]
+		symbol = c.symbolTable.Define(node.Identifier.Value)
+		if err := c.compileFunctionLiteral(fn, node.Identifier.Value); err != nil {
+			// a later input compiled with the same symbol table must not
+			// see the name, no value was stored for it
+			c.symbolTable.restore(node.Identifier.Value, previous, bound)
+			return err
+		}
+	} else {
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		symbol = c.symbolTable.Define(node.Identifier.Value)
+	}
+
+	if symbol.Scope == GlobalScope {
+		if symbol.Index > math.MaxUint16 {
+			return newError(node, "too many global bindings")
+		}
+		c.emit(code.OpSetGlobal, symbol.Index)
+	} else {
+		if symbol.Index > math.MaxUint8 {
+			return newError(node, "too many local bindings")
+		}
+		c.emit(code.OpSetLocal, symbol.Index)
+	}
+
+	return nil
+}
+
+/*
+	Expression compilation
+*/
+
+func (c *Compiler) compilePrefixExpression(node *ast.PrefixExpression) error {
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+
+	switch node.Operator {
+	case tokens.BANG:
+		c.emitAt(node, code.OpBang)
+	case tokens.MINUS:
+		c.emitAt(node, code.OpMinus)
+	case tokens.BITNOT:
+		c.emitAt(node, code.OpBitNot)
+	default:
+		return newError(node, "unknown operator %s", node.Operator)
+	}
+
+	return nil
+}
+
+var infixOperators = map[string]code.Opcode{
+	tokens.PLUS:         code.OpAdd,
+	tokens.MINUS:        code.OpSub,
+	tokens.MULTIPLY:     code.OpMul,
+	tokens.DIVIDE:       code.OpDiv,
+	tokens.MODULO:       code.OpMod,
+	tokens.POWER:        code.OpPow,
+	tokens.BITAND:       code.OpBitAnd,
+	tokens.BITOR:        code.OpBitOr,
+	tokens.BITXOR:       code.OpBitXor,
+	tokens.SHIFTLEFT:    code.OpShiftLeft,
+	tokens.SHIFTRIGHT:   code.OpShiftRight,
+	tokens.EQUAL:        code.OpEqual,
+	tokens.NOTEQUAL:     code.OpNotEqual,
+	tokens.LESS:         code.OpLessThan,
+	tokens.GREATER:      code.OpGreaterThan,
+	tokens.LESSEQUAL:    code.OpLessEqual,
+	tokens.GREATEREQUAL: code.OpGreaterEqual,
+}
+
+func (c *Compiler) compileInfixExpression(node *ast.InfixExpression) error {
+	if node.Operator == tokens.AND || node.Operator == tokens.OR {
+		return c.compileLogicalExpression(node)
+	}
+
+	op, ok := infixOperators[node.Operator]
+	if !ok {
+		return newError(node, "unknown operator %s", node.Operator)
+	}
+
+	if err := c.Compile(node.Left); err != nil {
+		return err
+	}
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+
+	c.emitAt(node, op)
+	return nil
+}
+
+// compileLogicalExpression jumps over the right operand when the left one
+// decides the result, operands are turned into booleans with a double bang.
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	if err := c.Compile(node.Left); err != nil {
+		return err
+	}
+
+	jumpNotTruthy := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if node.Operator == tokens.AND {
+		if err := c.compileBoolean(node.Right); err != nil {
+			return err
+		}
+	} else {
+		c.emit(code.OpTrue)
+	}
+
+	jump := c.emit(code.OpJump, 9999)
+	if err := c.changeOperand(node, jumpNotTruthy, len(c.currentInstructions())); err != nil {
+		return err
+	}
+
+	if node.Operator == tokens.AND {
+		c.emit(code.OpFalse)
+	} else if err := c.compileBoolean(node.Right); err != nil {
+		return err
+	}
+
+	return c.changeOperand(node, jump, len(c.currentInstructions()))
+}
+
+func (c *Compiler) compileBoolean(node ast.Expression) error {
+	if err := c.Compile(node); err != nil {
+		return err
+	}
+
+	c.emit(code.OpBang)
+	c.emit(code.OpBang)
+	return nil
+}
+
+// compileAssignExpression stores the new value of the variable and leaves
+// the assigned value on the stack. An element assignment leaves the updated
+// collection on top of the assigned value, from where it is stored.
+func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
+	variable, indices := node.Variable()
+	if variable == nil {
+		return newError(node, "cannot assign to %s", node.Target)
+	}
+
+	symbol, ok := c.symbolTable.Resolve(variable.Value)
+	switch {
+	case !ok:
+		return newError(node, "assignment to undeclared identifier: %s", variable.Value)
+	case symbol.Scope == BuiltinScope:
+		return newError(node, "cannot assign to builtin: %s", variable.Value)
+	case len(indices) > math.MaxUint8:
+		return newError(node, "too many nested indices")
+	}
+
+	operator := node.InfixOperator()
+	if len(indices) > 0 || operator != "" {
+		c.loadSymbol(symbol)
+	}
+	for _, index := range indices {
+		if err := c.Compile(index); err != nil {
+			return err
+		}
+	}
+	if err := c.Compile(node.Value); err != nil {
+		return err
+	}
+
+	switch {
+	case len(indices) > 0 && operator != "":
+		c.emitAt(node, code.OpUpdateIndex, len(indices), int(infixOperators[operator]))
+	case len(indices) > 0:
+		c.emitAt(node, code.OpSetIndex, len(indices))
+	case operator != "":
+		c.emitAt(node, infixOperators[operator])
+	}
+
+	c.storeSymbol(symbol)
+	if len(indices) == 0 {
+		c.loadSymbol(symbol)
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+
+	// the jump offsets are patched once the branches are compiled
+	jumpNotTruthy := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileBranch(node.Consequence); err != nil {
+		return err
+	}
+
+	jump := c.emit(code.OpJump, 9999)
+	if err := c.changeOperand(node, jumpNotTruthy, len(c.currentInstructions())); err != nil {
+		return err
+	}
+
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else if err := c.compileBranch(node.Alternative); err != nil {
+		return err
+	}
+
+	return c.changeOperand(node, jump, len(c.currentInstructions()))
+}
+
+// compileBranch leaves the value of the block on the stack, which is the
+// value of its last expression statement or null.
+func (c *Compiler) compileBranch(block *ast.BlockStatement) error {
+	if err := c.Compile(block); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastInstruction()
+	} else {
+		c.emit(code.OpNull)
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral, name string) error {
+	c.enterScope()
+
+	for _, p := range node.Parameters {
+		c.symbolTable.Define(p.Value)
+	}
+
+	if err := c.Compile(node.Body); err != nil {
+		c.leaveScope() // the compiler stays usable for the next input
+		return err
+	}
+
+	// the value of the last expression statement is returned implicitly
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastInstruction(code.Make(code.OpReturnValue))
+		c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) && !c.lastInstructionIs(code.OpReturn) {
+		c.emit(code.OpReturn)
+	}
+
+	freeSymbols := c.symbolTable.FreeSymbols
+	numLocals := c.symbolTable.NumDefinitions()
+	positions := c.scopes[c.scopeIndex].positions
+	instructions := c.leaveScope()
+
+	if numLocals > math.MaxUint8 {
+		return newError(node, "too many local bindings")
+	}
+	if len(freeSymbols) > math.MaxUint8 {
+		return newError(node, "too many captured variables")
+	}
+
+	// the cells of the free variables are pushed for OpClosure to capture
+	for _, s := range freeSymbols {
+		c.captureSymbol(s)
+	}
+
+	fn := &object.CompiledFunction{
+		Instructions:  instructions,
+		Positions:     positions,
+		NumLocals:     numLocals,
+		NumParameters: len(node.Parameters),
+		Name:          name,
+	}
+
+	index, err := c.addConstant(node, fn)
+	if err != nil {
+		return err
+	}
+
+	c.emit(code.OpClosure, index, len(freeSymbols))
+	return nil
+}
+
+/*
+	Helpers
+*/
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	}
+}
+
+// captureSymbol pushes the cell of a variable of the enclosing function,
+// which is either one of its locals or one of its own free variables.
+func (c *Compiler) captureSymbol(s Symbol) {
+	if s.Scope == LocalScope {
+		c.emit(code.OpCaptureLocal, s.Index)
+	} else {
+		c.emit(code.OpCaptureFree, s.Index)
+	}
+}
+
+// storeSymbol pops the value into the variable, assigning a free variable
+// updates the cell shared with the function declaring it.
+func (c *Compiler) storeSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpSetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpSetFree, s.Index)
+	}
+}
+
+func (c *Compiler) emitConstant(node ast.Node, obj object.Object) error {
+	index, err := c.addConstant(node, obj)
+	if err != nil {
+		return err
+	}
+
+	c.emit(code.OpConstant, index)
+	return nil
+}
+
+func (c *Compiler) addConstant(node ast.Node, obj object.Object) (int, error) {
+	if len(c.constants) > math.MaxUint16 {
+		return 0, newError(node, "too many constants")
+	}
+
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1, nil
+}
+
+// emit appends the instruction to the current scope and returns its position.
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	ins := code.Make(op, operands...)
+
+	scope := &c.scopes[c.scopeIndex]
+	pos := len(scope.instructions)
+	scope.instructions = append(scope.instructions, ins...)
+
+	scope.previousInstruction = scope.lastInstruction
+	scope.lastInstruction = EmittedInstruction{Opcode: op, Position: pos}
+
+	return pos
+}
+
+// emitAt emits an instruction that can fail at runtime and records the
+// position of the node, so the error can be reported at it.
+func (c *Compiler) emitAt(node ast.Node, op code.Opcode, operands ...int) int {
+	pos := c.emit(op, operands...)
+	c.scopes[c.scopeIndex].positions[pos] = node.Pos()
+	return pos
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastInstruction() {
+	scope := &c.scopes[c.scopeIndex]
+	scope.instructions = scope.instructions[:scope.lastInstruction.Position]
+	scope.lastInstruction = scope.previousInstruction
+}
+
+func (c *Compiler) replaceLastInstruction(ins []byte) {
+	scope := &c.scopes[c.scopeIndex]
+	copy(scope.instructions[scope.lastInstruction.Position:], ins)
+}
+
+// changeOperand patches the target of a jump, which must fit in its two
+// byte operand.
+func (c *Compiler) changeOperand(node ast.Node, pos int, operand int) error {
+	if operand > math.MaxUint16 {
+		return newError(node, "too many instructions")
+	}
+
+	op := code.Opcode(c.currentInstructions()[pos])
+	c.replaceInstruction(pos, code.Make(op, operand))
+	return nil
+}
+
+func (c *Compiler) replaceInstruction(pos int, ins []byte) {
+	copy(c.scopes[c.scopeIndex].instructions[pos:], ins)
+}
+
+func (c *Compiler) enterScope() {
+	c.scopes = append(c.scopes, CompilationScope{positions: code.SourceMap{}})
+	c.scopeIndex++
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
+func newError(node ast.Node, format string, args ...any) *Error {
+	return &Error{
+		Pos:     node.Pos(),
+		Message: fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,136 @@
+package compiler
+
+import (
+	"flag"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"language/lexer"
+	"language/parser"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func compileInput(t *testing.T, input string) (*Bytecode, error) {
+	p := parser.New(lexer.New(input))
+	program, err := p.Parse()
+	require.NoError(t, err, fmt.Sprintf("parsing input %s failed", input))
+
+	c := New()
+	if err := c.Compile(program); err != nil {
+		return nil, err
+	}
+	return c.Bytecode(), nil
+}
+
+// Test_Golden compiles every program in testdata and compares the
+// disassembly with the golden file next to it, run with -update to
+// rewrite the golden files after an intended change.
+func Test_Golden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.lang"))
+	require.NoError(t, err)
+	require.NotEmpty(t, inputs)
+
+	for _, input := range inputs {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			source, err := os.ReadFile(input)
+			require.NoError(t, err)
+
+			bytecode, err := compileInput(t, string(source))
+			require.NoError(t, err)
+			actual := Disassemble(bytecode)
+
+			golden := strings.TrimSuffix(input, ".lang") + ".golden"
+			if *update {
+				require.NoError(t, os.WriteFile(golden, []byte(actual), 0o644))
+			}
+
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), actual)
+		})
+	}
+}
+
+func Test_CompileErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+		pos string
+	}{
+		{in: "x;", out: "identifier not found: x", pos: "1:1"},
+		{in: "let a = fun() { b };", out: "identifier not found: b", pos: "1:17"},
+		{in: "let a = a;", out: "identifier not found: a", pos: "1:9"},
+		{in: "if (true) { 1 } else { y }", out: "identifier not found: y", pos: "1:24"},
+		{in: "x = 1;", out: "assignment to undeclared identifier: x", pos: "1:1"},
+		{in: "let a = [1];\nb[0] += a;", out: "assignment to undeclared identifier: b", pos: "2:1"},
+		{in: "len = 1;", out: "cannot assign to builtin: len", pos: "1:1"},
+	}
+
+	for _, test := range tests {
+		_, err := compileInput(t, test.in)
+		require.Error(t, err, test.in)
+
+		compileErr, ok := err.(*Error)
+		require.True(t, ok, fmt.Sprintf("expected compile error, got %T", err))
+		assert.Equal(t, test.out, compileErr.Message)
+		assert.Equal(t, test.pos, compileErr.Pos.String())
+	}
+}
+
+func Test_CompileWithState(t *testing.T) {
+	c := New()
+	p := parser.New(lexer.New("let a = 1;"))
+	program, err := p.Parse()
+	require.NoError(t, err)
+	require.NoError(t, c.Compile(program))
+
+	// a later input sees the globals and constants of the previous one
+	next := NewWithState(c.SymbolTable(), c.Bytecode().Constants)
+	p = parser.New(lexer.New("a + 2;"))
+	program, err = p.Parse()
+	require.NoError(t, err)
+	require.NoError(t, next.Compile(program))
+
+	bytecode := next.Bytecode()
+	assert.Equal(t, "0000 OpGetGlobal 0\n0003 OpConstant 1\n0006 OpAdd\n0007 OpPop\n", bytecode.Instructions.String())
+	assert.Len(t, bytecode.Constants, 2)
+}
+
+func Test_CompileErrorLeavesScope(t *testing.T) {
+	c := New()
+	program, err := parser.New(lexer.New("let f = fun() { fun() { missing } };")).Parse()
+	require.NoError(t, err)
+	require.Error(t, c.Compile(program))
+
+	// the failed function bodies don't leave the compiler in their scope
+	program, err = parser.New(lexer.New("let a = 1;")).Parse()
+	require.NoError(t, err)
+	require.NoError(t, c.Compile(program))
+
+	symbol, ok := c.SymbolTable().Resolve("a")
+	require.True(t, ok)
+	assert.Equal(t, GlobalScope, symbol.Scope)
+}
+
+func Test_OperandLimits(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: strings.Repeat("1;", math.MaxUint16+2), out: "too many constants"},
+		{in: "if (true) { " + strings.Repeat("true;", math.MaxUint16/2) + " }", out: "too many instructions"},
+		{in: "[" + strings.Repeat("true, ", math.MaxUint16) + "true]", out: "too many array elements"},
+	}
+
+	for _, test := range tests {
+		_, err := compileInput(t, test.in)
+		require.Error(t, err)
+		assert.Equal(t, test.out, err.Error())
+	}
+}
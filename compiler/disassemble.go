@@ -0,0 +1,40 @@
+package compiler
+
+import (
+	"fmt"
+	"language/object"
+	"strings"
+)
+
+// Disassemble prints the main instructions followed by the constant pool,
+// the instructions of compiled functions are printed below the constant.
+func Disassemble(b *Bytecode) string {
+	var out strings.Builder
+
+	out.WriteString(b.Instructions.String())
+
+	if len(b.Constants) > 0 {
+		out.WriteString("\nconstants:\n")
+	}
+	for i, c := range b.Constants {
+		fmt.Fprintf(&out, "%04d %s %s\n", i, c.Type(), describeConstant(c))
+
+		if fn, ok := c.(*object.CompiledFunction); ok {
+			fmt.Fprintf(&out, "     locals: %d\n", fn.NumLocals)
+			for _, line := range strings.SplitAfter(fn.Instructions.String(), "\n") {
+				if line != "" {
+					out.WriteString("     " + line)
+				}
+			}
+		}
+	}
+
+	return out.String()
+}
+
+func describeConstant(c object.Object) string {
+	if s, ok := c.(*object.String); ok {
+		return fmt.Sprintf("%q", s.Value)
+	}
+	return c.String()
+}
@@ -0,0 +1,106 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope  SymbolScope = "GLOBAL"
+	LocalScope   SymbolScope = "LOCAL"
+	BuiltinScope SymbolScope = "BUILTIN"
+	FreeScope    SymbolScope = "FREE"
+)
+
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+// SymbolTable resolves names to the storage they are kept in. The global
+// table has no outer table, every function body gets an enclosed table.
+type SymbolTable struct {
+	Outer       *SymbolTable
+	FreeSymbols []Symbol // symbols of enclosing functions captured by this one
+
+	store          map[string]Symbol
+	numDefinitions int
+}
+
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{
+		store: make(map[string]Symbol),
+	}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	table := NewSymbolTable()
+	table.Outer = outer
+	return table
+}
+
+// Define binds the name in the current table. Defining a name that is
+// already bound in the same table reuses its storage, so redeclaration
+// with let rebinds the name the same way the evaluator does.
+func (s *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := s.store[name]; ok && (symbol.Scope == GlobalScope || symbol.Scope == LocalScope) {
+		return symbol
+	}
+
+	symbol := Symbol{Name: name, Index: s.numDefinitions, Scope: LocalScope}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+	}
+
+	s.store[name] = symbol
+	s.numDefinitions++
+	return symbol
+}
+
+// restore undoes the last definition of the name, the symbol bound before
+// it, if any, is bound again. Nothing is undone when the definition reused
+// the storage of the name.
+func (s *SymbolTable) restore(name string, previous Symbol, bound bool) {
+	if bound && s.store[name] == previous {
+		return
+	}
+
+	if bound {
+		s.store[name] = previous
+	} else {
+		delete(s.store, name)
+	}
+	s.numDefinitions--
+}
+
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) NumDefinitions() int {
+	return s.numDefinitions
+}
+
+// Resolve looks the name up in this and the enclosing tables, locals of an
+// enclosing function are turned into free symbols of this one.
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	symbol, ok := s.store[name]
+	if ok || s.Outer == nil {
+		return symbol, ok
+	}
+
+	symbol, ok = s.Outer.Resolve(name)
+	if !ok || symbol.Scope == GlobalScope || symbol.Scope == BuiltinScope {
+		return symbol, ok
+	}
+
+	return s.defineFree(symbol), true
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1, Scope: FreeScope}
+	s.store[original.Name] = symbol
+	return symbol
+}
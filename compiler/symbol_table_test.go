@@ -0,0 +1,69 @@
+package compiler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Define(t *testing.T) {
+	global := NewSymbolTable()
+	assert.Equal(t, Symbol{Name: "a", Scope: GlobalScope, Index: 0}, global.Define("a"))
+	assert.Equal(t, Symbol{Name: "b", Scope: GlobalScope, Index: 1}, global.Define("b"))
+	assert.Equal(t, Symbol{Name: "a", Scope: GlobalScope, Index: 0}, global.Define("a"))
+	assert.Equal(t, 2, global.NumDefinitions())
+
+	local := NewEnclosedSymbolTable(global)
+	assert.Equal(t, Symbol{Name: "a", Scope: LocalScope, Index: 0}, local.Define("a"))
+	assert.Equal(t, Symbol{Name: "c", Scope: LocalScope, Index: 1}, local.Define("c"))
+}
+
+func Test_Resolve(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineBuiltin(0, "len")
+	global.Define("a")
+
+	first := NewEnclosedSymbolTable(global)
+	first.Define("b")
+
+	second := NewEnclosedSymbolTable(first)
+	second.Define("c")
+
+	tests := []struct {
+		name     string
+		expected Symbol
+	}{
+		{name: "len", expected: Symbol{Name: "len", Scope: BuiltinScope, Index: 0}},
+		{name: "a", expected: Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{name: "b", expected: Symbol{Name: "b", Scope: FreeScope, Index: 0}},
+		{name: "c", expected: Symbol{Name: "c", Scope: LocalScope, Index: 0}},
+	}
+
+	for _, test := range tests {
+		symbol, ok := second.Resolve(test.name)
+		assert.True(t, ok, test.name)
+		assert.Equal(t, test.expected, symbol)
+	}
+
+	assert.Equal(t, []Symbol{{Name: "b", Scope: LocalScope, Index: 0}}, second.FreeSymbols)
+
+	_, ok := second.Resolve("missing")
+	assert.False(t, ok)
+}
+
+func Test_ResolveNestedFree(t *testing.T) {
+	global := NewSymbolTable()
+
+	first := NewEnclosedSymbolTable(global)
+	first.Define("a")
+
+	second := NewEnclosedSymbolTable(first)
+	third := NewEnclosedSymbolTable(second)
+
+	symbol, ok := third.Resolve("a")
+	assert.True(t, ok)
+	assert.Equal(t, Symbol{Name: "a", Scope: FreeScope, Index: 0}, symbol)
+
+	// the enclosing function captures the variable so it can pass it on
+	assert.Equal(t, []Symbol{{Name: "a", Scope: LocalScope, Index: 0}}, second.FreeSymbols)
+	assert.Equal(t, []Symbol{{Name: "a", Scope: FreeScope, Index: 0}}, third.FreeSymbols)
+}
@@ -0,0 +1,45 @@
+package diagnostics
+
+import (
+	"fmt"
+	"language/tokens"
+	"strings"
+)
+
+// Format renders the message prefixed by the position, followed by the
+// source line the position points to and a caret marking the column.
+func Format(source string, pos tokens.Position, message string) string {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("%s: %s", pos, message))
+
+	lines := strings.Split(source, "\n")
+	if !pos.IsValid() || pos.Line > len(lines) {
+		return out.String()
+	}
+
+	line := strings.TrimRight(lines[pos.Line-1], "\r")
+	out.WriteString("\n    ")
+	out.WriteString(line)
+	out.WriteString("\n    ")
+	out.WriteString(caretIndent(line, pos.Column))
+	out.WriteString("^")
+
+	return out.String()
+}
+
+// caretIndent keeps tabs from the line, so the caret is aligned with the
+// column no matter how wide tabs are rendered. Columns count runes.
+func caretIndent(line string, column int) string {
+	var indent strings.Builder
+	for i, r := range []rune(line) {
+		if i >= column-1 {
+			break
+		}
+		if r == '\t' {
+			indent.WriteByte('\t')
+		} else {
+			indent.WriteByte(' ')
+		}
+	}
+	return indent.String()
+}
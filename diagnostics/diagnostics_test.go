@@ -0,0 +1,45 @@
+package diagnostics
+
+import (
+	"github.com/stretchr/testify/assert"
+	"language/tokens"
+	"testing"
+)
+
+func Test_Format(t *testing.T) {
+	tests := []struct {
+		source  string
+		pos     tokens.Position
+		message string
+		out     string
+	}{
+		{
+			source:  "let x = 1;\nlet y 2;",
+			pos:     tokens.Position{Line: 2, Column: 7, Offset: 17},
+			message: "expected =, got INT",
+			out:     "2:7: expected =, got INT\n    let y 2;\n          ^",
+		},
+		{
+			source:  "\tfoo(",
+			pos:     tokens.Position{File: "main.lang", Line: 1, Column: 6, Offset: 5},
+			message: "expected ), got EOF",
+			out:     "main.lang:1:6: expected ), got EOF\n    \tfoo(\n    \t    ^",
+		},
+		{
+			source:  "let größe = \"😀\" + x;",
+			pos:     tokens.Position{Line: 1, Column: 19, Offset: 24},
+			message: "identifier not found: x",
+			out:     "1:19: identifier not found: x\n    let größe = \"😀\" + x;\n                      ^",
+		},
+		{
+			source:  "1;",
+			pos:     tokens.Position{},
+			message: "unknown position",
+			out:     "0:0: unknown position",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.out, Format(test.source, test.pos, test.message))
+	}
+}
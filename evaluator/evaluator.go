@@ -0,0 +1,363 @@
+package evaluator
+
+import (
+	"fmt"
+	"language/ast"
+	"language/object"
+	"language/tokens"
+)
+
+var (
+	NULL  = object.NullValue
+	TRUE  = object.TrueValue
+	FALSE = object.FalseValue
+)
+
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+
+	// errors are located at the innermost node they were produced by
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() && node != nil {
+		err.Pos = node.Pos()
+	}
+
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
+	switch node := node.(type) {
+	case *ast.Program:
+		return evalProgram(node, env)
+	case *ast.ExpressionStatement:
+		return Eval(node.Expression, env)
+	case *ast.LetStatement:
+		return evalLetStatement(node, env)
+	case *ast.ReturnStatement:
+		return evalReturnStatement(node, env)
+	case *ast.BlockStatement:
+		return evalBlockStatement(node, env)
+	case *ast.PrefixExpression:
+		right := Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+		return env.Runtime().Prefix(node.Operator, right)
+	case *ast.InfixExpression:
+		if node.Operator == tokens.AND || node.Operator == tokens.OR {
+			return evalLogicalExpression(node, env)
+		}
+
+		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		right := Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+		return env.Runtime().Infix(node.Operator, left, right)
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+	case *ast.Identifier:
+		return evalIdentifier(node, env)
+	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return &object.BigInteger{Value: node.Big}
+		}
+		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+	case *ast.BooleanLiteral:
+		return object.NativeBoolean(node.Value)
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
+	case *ast.IfExpression:
+		return evalIfExpression(node, env)
+	case *ast.ArrayLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return &object.Array{Elements: elements}
+	case *ast.HashLiteral:
+		return evalHashLiteral(node, env)
+	case *ast.IndexExpression:
+		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return index
+		}
+		return object.Index(left, index)
+	case *ast.FunctionLiteral:
+		return &object.Function{Parameters: node.Parameters, Body: node.Body, Env: env}
+	case *ast.CallExpression:
+		function := Eval(node.Function, env)
+		if isError(function) {
+			return function
+		}
+		args := evalExpressions(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return applyFunction(function, args, env.Runtime())
+	}
+
+	return newError("unknown node %T", node)
+}
+
+func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	var result object.Object
+
+	for _, st := range program.Statements {
+		result = Eval(st, env)
+
+		switch result := result.(type) {
+		case *object.ReturnValue:
+			return result.Value
+		case *object.Error:
+			return result
+		}
+	}
+
+	return result
+}
+
+/*
+	Statement evaluation
+*/
+
+func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+	var result object.Object
+
+	for _, st := range block.Statements {
+		result = Eval(st, env)
+
+		// return values and errors are passed up unwrapped, so they also
+		// stop the evaluation of all the enclosing blocks
+		if result != nil && (result.Type() == object.RETURN_VALUE || result.Type() == object.ERROR) {
+			return result
+		}
+	}
+
+	return result
+}
+
+func evalLetStatement(st *ast.LetStatement, env *object.Environment) object.Object {
+	val := Eval(st.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	env.Set(st.Identifier.Value, val)
+	return nil
+}
+
+func evalReturnStatement(st *ast.ReturnStatement, env *object.Environment) object.Object {
+	if st.Value == nil {
+		return &object.ReturnValue{Value: NULL}
+	}
+
+	val := Eval(st.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	return &object.ReturnValue{Value: val}
+}
+
+/*
+	Expression evaluation
+*/
+
+func evalIdentifier(ident *ast.Identifier, env *object.Environment) object.Object {
+	if val, ok := env.Get(ident.Value); ok {
+		return val
+	}
+
+	if builtin, ok := object.LookupBuiltin(ident.Value); ok {
+		return builtin
+	}
+
+	return newError("identifier not found: %s", ident.Value)
+}
+
+// evalAssignExpression rebinds the variable of the target and evaluates to
+// the assigned value. Collections are never modified in place, assigning
+// to an element rebinds the variable to an updated copy.
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	variable, indexExpressions := node.Variable()
+	if variable == nil {
+		return newError("cannot assign to %s", node.Target)
+	}
+
+	current, ok := env.Get(variable.Value)
+	if !ok {
+		if _, ok := object.LookupBuiltin(variable.Value); ok {
+			return newError("cannot assign to builtin: %s", variable.Value)
+		}
+		return newError("assignment to undeclared identifier: %s", variable.Value)
+	}
+
+	indices := evalExpressions(indexExpressions, env)
+	if len(indices) == 1 && isError(indices[0]) {
+		return indices[0]
+	}
+
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	updated, assigned := env.Runtime().Assign(current, indices, node.InfixOperator(), value)
+	if isError(updated) {
+		return updated
+	}
+
+	env.Assign(variable.Value, updated)
+	return assigned
+}
+
+// evalLogicalExpression evaluates the right operand only when the left one
+// doesn't decide the result, the result is always a boolean.
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	if object.IsTruthy(left) == (node.Operator == tokens.OR) {
+		return object.NativeBoolean(object.IsTruthy(left))
+	}
+
+	right := Eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+	return object.NativeBoolean(object.IsTruthy(right))
+}
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newErrorAt(pair.Key, "unusable as hash key: %s", key.Type())
+		}
+
+		val := Eval(pair.Value, env)
+		if isError(val) {
+			return val
+		}
+
+		hash.Set(hashKey, val)
+	}
+
+	return hash
+}
+
+func evalIfExpression(exp *ast.IfExpression, env *object.Environment) object.Object {
+	condition := Eval(exp.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	var result object.Object
+	switch {
+	case object.IsTruthy(condition):
+		result = Eval(exp.Consequence, env)
+	case exp.Alternative != nil:
+		result = Eval(exp.Alternative, env)
+	}
+
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+func evalExpressions(expressions []ast.Expression, env *object.Environment) []object.Object {
+	result := make([]object.Object, 0, len(expressions))
+
+	for _, exp := range expressions {
+		val := Eval(exp, env)
+		if isError(val) {
+			return []object.Object{val}
+		}
+		result = append(result, val)
+	}
+
+	return result
+}
+
+func applyFunction(fn object.Object, args []object.Object, rt *object.Runtime) object.Object {
+	if builtin, ok := fn.(*object.Builtin); ok {
+		if result := builtin.Fn(rt, args...); result != nil {
+			return result
+		}
+		return NULL
+	}
+
+	function, ok := fn.(*object.Function)
+	if !ok {
+		return newError("not a function: %s", fn.Type())
+	}
+
+	if len(args) != len(function.Parameters) {
+		return newError(
+			"wrong number of arguments: expected %d, got %d",
+			len(function.Parameters),
+			len(args),
+		)
+	}
+
+	// the depth is limited so deep recursion fails with an error, like in
+	// the virtual machine, instead of exhausting the Go stack
+	if !rt.EnterCall() {
+		return newError("stack overflow")
+	}
+	defer rt.LeaveCall()
+
+	env := object.NewEnclosedEnvironment(function.Env)
+	for i, param := range function.Parameters {
+		env.Set(param.Value, args[i])
+	}
+
+	result := Eval(function.Body, env)
+	if returnValue, ok := result.(*object.ReturnValue); ok {
+		return returnValue.Value
+	}
+	if result == nil {
+		return NULL
+	}
+
+	return result
+}
+
+/*
+	Helpers
+*/
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ERROR
+}
+
+func newError(format string, args ...any) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// newErrorAt creates an error located at the node, for errors caused by a
+// child of the node being evaluated.
+func newErrorAt(node ast.Node, format string, args ...any) *object.Error {
+	err := newError(format, args...)
+	err.Pos = node.Pos()
+	return err
+}
@@ -0,0 +1,738 @@
+package evaluator
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"language/ast"
+	"language/lexer"
+	"language/object"
+	"language/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func parseInput(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program, err := p.Parse()
+	require.NoError(t, err, fmt.Sprintf("parsing input %s failed", input))
+	return program
+}
+
+func evalInput(t *testing.T, input string) object.Object {
+	return Eval(parseInput(t, input), object.NewEnvironment())
+}
+
+// Test_Programs evaluates the programs in testdata/programs.lang, which are
+// separated by blank lines, and compares the results with the golden file
+// next to it, run with -update to rewrite it after an intended change. The
+// virtual machine runs the same programs to check it matches the evaluator.
+func Test_Programs(t *testing.T) {
+	source, err := os.ReadFile(filepath.Join("testdata", "programs.lang"))
+	require.NoError(t, err)
+
+	var results []string
+	for _, input := range strings.Split(strings.TrimSpace(string(source)), "\n\n") {
+		rt := &object.Runtime{Stdout: io.Discard}
+		result := Eval(parseInput(t, input), object.NewEnvironmentWithRuntime(rt))
+
+		switch result := result.(type) {
+		case nil:
+			results = append(results, fmt.Sprintf("%s\n=> no value\n", input))
+		case *object.Error:
+			results = append(results, fmt.Sprintf("%s\n=> %s: error: %s\n", input, result.Pos, result.Message))
+		default:
+			results = append(results, fmt.Sprintf("%s\n=> %s\n", input, result))
+		}
+	}
+	actual := strings.Join(results, "\n")
+
+	golden := filepath.Join("testdata", "programs.golden")
+	if *update {
+		require.NoError(t, os.WriteFile(golden, []byte(actual), 0o644))
+	}
+
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), actual)
+}
+
+func assertInteger(t *testing.T, obj object.Object, value int64) {
+	integer, ok := obj.(*object.Integer)
+	require.True(t, ok, fmt.Sprintf("expected integer, got %T (%v)", obj, obj))
+	assert.Equal(t, value, integer.Value)
+}
+
+func assertBoolean(t *testing.T, obj object.Object, value bool) {
+	boolean, ok := obj.(*object.Boolean)
+	require.True(t, ok, fmt.Sprintf("expected boolean, got %T (%v)", obj, obj))
+	assert.Equal(t, value, boolean.Value)
+}
+
+func assertError(t *testing.T, obj object.Object, message string) {
+	err, ok := obj.(*object.Error)
+	require.True(t, ok, fmt.Sprintf("expected error, got %T (%v)", obj, obj))
+	assert.Equal(t, message, err.Message)
+}
+
+func Test_IntegerExpression(t *testing.T) {
+	tests := []struct {
+		in  string
+		out int64
+	}{
+		{in: "5;", out: 5},
+		{in: "1337;", out: 1337},
+		{in: "-5;", out: -5},
+		{in: "--5;", out: 5},
+		{in: "5 + 5 + 5 - 10;", out: 5},
+		{in: "2 * 2 * 2;", out: 8},
+		{in: "-50 + 100 + -50;", out: 0},
+		{in: "5 + 2 * 10;", out: 25},
+		{in: "(5 + 2) * 10;", out: 70},
+		{in: "50 / 2 * 2 + 10;", out: 60},
+		{in: "3 * (3 * 3) + 10;", out: 37},
+		{in: "(5 + 10 * 2 + 15 / 3) * 2 + -10;", out: 50},
+		{in: "0xff + 0o7 + 0b11 + 1_000;", out: 1265},
+		{in: "7 % 3;", out: 1},
+		{in: "-7 % 3;", out: -1},
+		{in: "7 % -3;", out: 1},
+		{in: "2 ** 10;", out: 1024},
+		{in: "2 ** 3 ** 2;", out: 512},
+		{in: "-2 ** 2;", out: -4},
+		{in: "(-2) ** 3;", out: -8},
+		{in: "5 ** 0;", out: 1},
+		{in: "1 + 2 * 3 ** 2 % 5;", out: 4},
+		{in: "0b1100 & 0b1010;", out: 8},
+		{in: "0b1100 | 0b1010;", out: 14},
+		{in: "0b1100 ^ 0b1010;", out: 6},
+		{in: "~5;", out: -6},
+		{in: "~-1;", out: 0},
+		{in: "1 << 10;", out: 1024},
+		{in: "1 << 64;", out: 0},
+		{in: "-16 >> 2;", out: -4},
+		{in: "0xff >> 100;", out: 0},
+		{in: "0xff & ~0xf | 1 << 2;", out: 244},
+	}
+
+	for _, test := range tests {
+		assertInteger(t, evalInput(t, test.in), test.out)
+	}
+}
+
+func Test_FloatExpression(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "3.14", out: "3.14"},
+		{in: "-.5", out: "-0.5"},
+		{in: "1 / 2.0", out: "0.5"},
+		{in: "10.0 / 4", out: "2.5"},
+		{in: "1.5 + 1.5", out: "3.0"},
+		{in: "2 * 1.25 - 1", out: "1.5"},
+		{in: "1e-9 * 2", out: "2e-09"},
+		{in: "1e20 * 100", out: "1e+22"},
+		{in: "1 == 1.0", out: "true"},
+		{in: "1.5 != 1.5", out: "false"},
+		{in: "1 < 1.5", out: "true"},
+		{in: "2.5 > 3", out: "false"},
+		{in: "5.5 % 2", out: "1.5"},
+		{in: "2 ** 0.5 ** 2", out: "1.189207115002721"},
+		{in: "2 ** -1", out: "0.5"},
+		{in: "1.5 <= 1.5", out: "true"},
+		{in: "2 >= 2.5", out: "false"},
+	}
+
+	for _, test := range tests {
+		obj := evalInput(t, test.in)
+		assert.Equal(t, test.out, obj.String(), test.in)
+	}
+
+	assertError(t, evalInput(t, "1.5 / 0"), "division by zero: 1.5 / 0")
+	assertError(t, evalInput(t, "1 / 0.0"), "division by zero: 1 / 0.0")
+	assertError(t, evalInput(t, "1.5 % 0"), "division by zero: 1.5 % 0")
+	assertError(t, evalInput(t, `1.5 + "a"`), "type mismatch: FLOAT + STRING")
+}
+
+func Test_BigIntegers(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "9223372036854775807 + 1", out: "9223372036854775808"},
+		{in: "-9223372036854775807 - 2", out: "-9223372036854775809"},
+		{in: "9223372036854775807 * 9223372036854775807", out: "85070591730234615847396907784232501249"},
+		{in: "99999999999999999999 / 3", out: "33333333333333333333"},
+		{in: "0xffffffffffffffffff", out: "4722366482869645213695"},
+		{in: "99999999999999999999 - 99999999999999999998", out: "1"},
+		{in: "99999999999999999999 > 9223372036854775807", out: "true"},
+		{in: "99999999999999999999 == 99_999_999_999_999_999_999", out: "true"},
+		{in: "99999999999999999999 * 0.5", out: "50000000000000000000.0"},
+		{in: "2 ** 64", out: "18446744073709551616"},
+		{in: "(-3) ** 41", out: "-36472996377170786403"},
+		{in: "99999999999999999999 % 7", out: "1"},
+		{in: "99999999999999999999 ** -1", out: "1e-20"},
+		{in: "99999999999999999999 >= 99999999999999999999", out: "true"},
+		{in: "1 << 64", out: "18446744073709551616"},
+		{in: "-1 << 63", out: "-9223372036854775808"},
+		{in: "(1 << 100) >> 99", out: "2"},
+		{in: "~99999999999999999999", out: "-100000000000000000000"},
+		{in: "99999999999999999999 & 0xffff", out: "65535"},
+		{in: "-(1 << 70) | 1", out: "-1180591620717411303423"},
+		{in: `{99999999999999999999: "big"}[99999999999999999998 + 1]`, out: "big"},
+		{in: "2 ** 10000000000", out: "error: exponent too large: 10000000000"},
+		{in: "1 << 9223372036854775807", out: "error: shift count too large: 9223372036854775807"},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.in), parser.WithBigIntegers())
+		program, err := p.Parse()
+		require.NoError(t, err, test.in)
+
+		env := object.NewEnvironmentWithRuntime(&object.Runtime{BigIntegers: true})
+		assert.Equal(t, test.out, Eval(program, env).String(), test.in)
+	}
+}
+
+func Test_BooleanExpression(t *testing.T) {
+	tests := []struct {
+		in  string
+		out bool
+	}{
+		{in: "true;", out: true},
+		{in: "false;", out: false},
+		{in: "1 < 2;", out: true},
+		{in: "1 > 2;", out: false},
+		{in: "1 == 1;", out: true},
+		{in: "1 != 1;", out: false},
+		{in: "1 != 2;", out: true},
+		{in: "true == true;", out: true},
+		{in: "true != false;", out: true},
+		{in: "false == true;", out: false},
+		{in: "(1 < 2) == true;", out: true},
+		{in: "(1 > 2) == true;", out: false},
+		{in: "1 <= 1;", out: true},
+		{in: "2 <= 1;", out: false},
+		{in: "1 >= 2;", out: false},
+		{in: "2 >= 2;", out: true},
+	}
+
+	for _, test := range tests {
+		assertBoolean(t, evalInput(t, test.in), test.out)
+	}
+}
+
+func Test_BangOperator(t *testing.T) {
+	tests := []struct {
+		in  string
+		out bool
+	}{
+		{in: "!true;", out: false},
+		{in: "!false;", out: true},
+		{in: "!5;", out: false},
+		{in: "!!true;", out: true},
+		{in: "!!5;", out: true},
+	}
+
+	for _, test := range tests {
+		assertBoolean(t, evalInput(t, test.in), test.out)
+	}
+}
+
+func Test_LogicalOperators(t *testing.T) {
+	tests := []struct {
+		in  string
+		out bool
+	}{
+		{in: "true && true", out: true},
+		{in: "true && false", out: false},
+		{in: "false || true", out: true},
+		{in: "false || false", out: false},
+		{in: "1 && \"a\"", out: true},
+		{in: "if (false) { 1 } || false", out: false},
+		{in: "1 < 2 && 2 < 3 || false", out: true},
+		// the right operand is not evaluated when the left one decides
+		{in: "false && 1 / 0", out: false},
+		{in: "true || 1 / 0", out: true},
+	}
+
+	for _, test := range tests {
+		assertBoolean(t, evalInput(t, test.in), test.out)
+	}
+
+	assertError(t, evalInput(t, "true && 1 / 0"), "division by zero: 1 / 0")
+	assertError(t, evalInput(t, "false || 1 / 0"), "division by zero: 1 / 0")
+}
+
+func Test_LetStatement(t *testing.T) {
+	tests := []struct {
+		in  string
+		out int64
+	}{
+		{in: "let a = 5; a;", out: 5},
+		{in: "let a = 5 * 5; a;", out: 25},
+		{in: "let a = 5; let b = a; b;", out: 5},
+		{in: "let a = 5; let b = a; let c = a + b + 5; c;", out: 15},
+		{in: "let x = 2; x * 3;", out: 6},
+		{in: "let x = 1; let x = x + 1; x;", out: 2},
+	}
+
+	for _, test := range tests {
+		assertInteger(t, evalInput(t, test.in), test.out)
+	}
+}
+
+func Test_AssignExpression(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "let x = 1; x = x + 1; x", out: "2"},
+		{in: "let x = 1; x = 5", out: "5"},
+		{in: "let x = 10; x += 2; x -= 4; x *= 3; x /= 6; x", out: "4"},
+		{in: `let s = "a"; s += "b"; s`, out: "ab"},
+		{in: "let a = 1; let b = 2; a = b = 3; [a, b]", out: "[3, 3]"},
+		{in: "let arr = [1, 2, 3]; arr[0] = 5; arr", out: "[5, 2, 3]"},
+		{in: "let arr = [1, 2]; arr[1] += 10", out: "12"},
+		{in: "let m = [[1, 2], [3, 4]]; m[1][0] *= 5; m", out: "[[1, 2], [15, 4]]"},
+		{in: `let h = {"a": 1}; h["b"] = 2; h["a"] += 1; h`, out: `{a: 2, b: 2}`},
+		{in: `let h = {"a": [1]}; h["a"][0] = 2; h`, out: `{a: [2]}`},
+		// collections are copied, other references keep the previous value
+		{in: "let a = [1]; let b = a; a[0] = 2; [a, b]", out: "[[2], [1]]"},
+		// functions rebind the variable of the scope it is defined in
+		{in: "let count = 0; let inc = fun() { count += 1 }; inc(); inc(); count", out: "2"},
+		{in: "let x = 1; let f = fun() { let x = 2; x = 3 }; f(); x", out: "1"},
+		{in: "let i = 0; let arr = [0, 0]; arr[i = 1] = 7; [i, arr]", out: "[1, [0, 7]]"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.out, evalInput(t, test.in).String(), test.in)
+	}
+
+	assertError(t, evalInput(t, "x = 1"), "assignment to undeclared identifier: x")
+	assertError(t, evalInput(t, "len = 1"), "cannot assign to builtin: len")
+	assertError(t, evalInput(t, "let x = 1; x += true"), "type mismatch: INTEGER + BOOLEAN")
+	assertError(t, evalInput(t, "let x = 1; x /= 0"), "division by zero: 1 / 0")
+	assertError(t, evalInput(t, "let a = [1]; a[1] = 2"), "index out of range: 1 with length 1")
+	assertError(t, evalInput(t, `let a = [1]; a["0"] = 2`), "array index must be INTEGER, got STRING")
+	assertError(t, evalInput(t, `let h = {}; h[[1]] = 2`), "unusable as hash key: ARRAY")
+	assertError(t, evalInput(t, "let n = 1; n[0] = 2"), "index operator not supported: INTEGER")
+	assertError(t, evalInput(t, `let h = {}; h["a"][0] = 1`), "index operator not supported: NULL")
+}
+
+func Test_ReturnStatement(t *testing.T) {
+	obj := evalInput(t, "return;")
+	assert.Equal(t, NULL, obj)
+}
+
+func Test_Errors(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "5 + true;", out: "type mismatch: INTEGER + BOOLEAN"},
+		{in: "5 + true; 5;", out: "type mismatch: INTEGER + BOOLEAN"},
+		{in: "-true;", out: "unknown operator: -BOOLEAN"},
+		{in: "true + false;", out: "unknown operator: BOOLEAN + BOOLEAN"},
+		{in: "5; true + false; 5;", out: "unknown operator: BOOLEAN + BOOLEAN"},
+		{in: "10 / 0;", out: "division by zero: 10 / 0"},
+		{in: "10 % 0;", out: "division by zero: 10 % 0"},
+		{in: "1.5 & 1;", out: "unknown operator: FLOAT & INTEGER"},
+		{in: "true | false;", out: "unknown operator: BOOLEAN | BOOLEAN"},
+		{in: "1 ^ true;", out: "type mismatch: INTEGER ^ BOOLEAN"},
+		{in: "~1.5;", out: "unknown operator: ~FLOAT"},
+		{in: "~true;", out: "unknown operator: ~BOOLEAN"},
+		{in: "1 << -1;", out: "negative shift count: -1"},
+		{in: "1 >> -1;", out: "negative shift count: -1"},
+		{in: "foo;", out: "identifier not found: foo"},
+		{in: "let a = b + 1;", out: "identifier not found: b"},
+		{in: "let a = -true; a;", out: "unknown operator: -BOOLEAN"},
+	}
+
+	for _, test := range tests {
+		assertError(t, evalInput(t, test.in), test.out)
+	}
+}
+
+func Test_FunctionObject(t *testing.T) {
+	obj := evalInput(t, "fun(x) { x + 2; };")
+
+	fn, ok := obj.(*object.Function)
+	require.True(t, ok, fmt.Sprintf("expected function, got %T (%v)", obj, obj))
+
+	require.Len(t, fn.Parameters, 1)
+	assert.Equal(t, "x", fn.Parameters[0].String())
+	assert.Equal(t, "{ (x + 2) }", fn.Body.String())
+}
+
+func Test_FunctionApplication(t *testing.T) {
+	tests := []struct {
+		in  string
+		out int64
+	}{
+		{in: "let identity = fun(x) { x; }; identity(5);", out: 5},
+		{in: "let double = fun(x) { x * 2; }; double(5);", out: 10},
+		{in: "let add = fun(x, y) { x + y; }; add(5, 5);", out: 10},
+		{in: "let add = fun(x, y) { x + y; }; add(5 + 5, add(5, 5));", out: 20},
+		{in: "fun(x) { x; }(5);", out: 5},
+		{in: "let x = 10; let f = fun(x) { x }; f(1) + x;", out: 11},
+	}
+
+	for _, test := range tests {
+		assertInteger(t, evalInput(t, test.in), test.out)
+	}
+}
+
+func Test_Closures(t *testing.T) {
+	tests := []struct {
+		in  string
+		out int64
+	}{
+		{
+			in: `
+				let newAdder = fun(x) { fun(y) { x + y }; };
+				let addTwo = newAdder(2);
+				addTwo(3);
+			`,
+			out: 5,
+		},
+		{
+			in: `
+				let a = 1;
+				let f = fun() { a };
+				let g = fun(a) { f() };
+				g(100);
+			`,
+			out: 1,
+		},
+		{
+			in: `
+				let apply = fun(f, x) { f(x) };
+				let square = fun(x) { x * x };
+				apply(square, 4);
+			`,
+			out: 16,
+		},
+	}
+
+	for _, test := range tests {
+		assertInteger(t, evalInput(t, test.in), test.out)
+	}
+}
+
+func Test_FunctionErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "let f = fun(x) { x }; f();", out: "wrong number of arguments: expected 1, got 0"},
+		{in: "let f = 5; f(1);", out: "not a function: INTEGER"},
+		{in: "let f = fun(x) { x }; f(y);", out: "identifier not found: y"},
+		{in: "let f = fun() { -true }; f();", out: "unknown operator: -BOOLEAN"},
+		{in: "let f = fun() { f() }; f();", out: "stack overflow"},
+	}
+
+	for _, test := range tests {
+		assertError(t, evalInput(t, test.in), test.out)
+	}
+}
+
+func Test_IfElseExpression(t *testing.T) {
+	tests := []struct {
+		in  string
+		out any
+	}{
+		{in: "if (true) { 10 };", out: 10},
+		{in: "if (false) { 10 };", out: nil},
+		{in: "if (1) { 10 };", out: 10},
+		{in: "if (1 < 2) { 10 };", out: 10},
+		{in: "if (1 > 2) { 10 };", out: nil},
+		{in: "if (1 > 2) { 10 } else { 20 };", out: 20},
+		{in: "if (1 < 2) { 10 } else { 20 };", out: 10},
+		{in: "let x = 5; if (x > 1) { 10 } else { 20 };", out: 10},
+		{in: "let x = 0; if (x > 1) { 10 } else if (x > -1) { 30 } else { 20 };", out: 30},
+		{in: "if (true) { let a = 1; };", out: nil},
+		{in: "let fact = fun(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(5);", out: 120},
+	}
+
+	for _, test := range tests {
+		obj := evalInput(t, test.in)
+		if test.out == nil {
+			assert.Equal(t, NULL, obj, test.in)
+		} else {
+			assertInteger(t, obj, int64(test.out.(int)))
+		}
+	}
+}
+
+func Test_ReturnValues(t *testing.T) {
+	tests := []struct {
+		in  string
+		out int64
+	}{
+		{in: "return 10;", out: 10},
+		{in: "return 10; 9;", out: 10},
+		{in: "return 2 * 5; 9;", out: 10},
+		{in: "9; return 2 * 5; 9;", out: 10},
+		{in: "if (10 > 1) { if (10 > 1) { return 10; } return 1; }", out: 10},
+		{in: "let f = fun(x) { return x; x + 10; }; f(10);", out: 10},
+		{in: "let f = fun(x) { let result = x + 10; return result; return 10; }; f(10);", out: 20},
+		{in: "let f = fun() { if (true) { return 1; } 2 }; f() + f();", out: 2},
+	}
+
+	for _, test := range tests {
+		assertInteger(t, evalInput(t, test.in), test.out)
+	}
+
+	assert.Equal(t, NULL, evalInput(t, "let f = fun() { return; 1 }; f();"))
+}
+
+func Test_StringExpression(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: `"hello world";`, out: "hello world"},
+		{in: `"hello" + " " + "world";`, out: "hello world"},
+		{in: `let greet = fun(name) { "hi " + name }; greet("bob");`, out: "hi bob"},
+		{in: `"line\n" + "\u{263A}";`, out: "line\n☺"},
+	}
+
+	for _, test := range tests {
+		obj := evalInput(t, test.in)
+		str, ok := obj.(*object.String)
+		require.True(t, ok, fmt.Sprintf("expected string, got %T (%v)", obj, obj))
+		assert.Equal(t, test.out, str.Value)
+	}
+
+	assertBoolean(t, evalInput(t, `"a" == "a";`), true)
+	assertBoolean(t, evalInput(t, `"a" != "a";`), false)
+	assertError(t, evalInput(t, `"a" - "b";`), "unknown operator: STRING - STRING")
+	assertError(t, evalInput(t, `"a" + 1;`), "type mismatch: STRING + INTEGER")
+}
+
+func Test_ErrorPosition(t *testing.T) {
+	tests := []struct {
+		in  string
+		pos string
+	}{
+		{in: "foo;", pos: "1:1"},
+		{in: "let a = 1;\nlet b = a + c;", pos: "2:13"},
+		{in: "let f = fun() {\n  -true\n};\nf();", pos: "2:3"},
+		{in: "1 + (2 + true);", pos: "1:6"},
+		{in: "let f = fun(n) { 1 + f(n + 1) }; f(0);", pos: "1:22"},
+	}
+
+	for _, test := range tests {
+		obj := evalInput(t, test.in)
+		err, ok := obj.(*object.Error)
+		require.True(t, ok, fmt.Sprintf("expected error, got %T (%v)", obj, obj))
+		assert.Equal(t, test.pos, err.Pos.String(), test.in)
+	}
+}
+
+func Test_BuiltinFunctions(t *testing.T) {
+	program, err := parser.New(lexer.New(`puts("hello", 1 + 2); puts();`)).Parse()
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	obj := Eval(program, object.NewEnvironmentWithRuntime(&object.Runtime{Stdout: &out}))
+	assert.Equal(t, NULL, obj)
+	assert.Equal(t, "hello\n3\n", out.String())
+
+	assert.Equal(t, "builtin puts", evalInput(t, "puts;").String())
+	assertInteger(t, evalInput(t, "let puts = 1; puts;"), 1)
+}
+
+func Test_ArrayLiteral(t *testing.T) {
+	obj := evalInput(t, "[1, 2 * 2, 3 + 3]")
+
+	array, ok := obj.(*object.Array)
+	require.True(t, ok, fmt.Sprintf("expected array, got %T (%v)", obj, obj))
+
+	require.Len(t, array.Elements, 3)
+	assertInteger(t, array.Elements[0], 1)
+	assertInteger(t, array.Elements[1], 4)
+	assertInteger(t, array.Elements[2], 6)
+	assert.Equal(t, "[1, 4, 6]", array.String())
+}
+
+func Test_ArrayIndexExpression(t *testing.T) {
+	tests := []struct {
+		in  string
+		out int64
+	}{
+		{in: "[1, 2, 3][0]", out: 1},
+		{in: "[1, 2, 3][2]", out: 3},
+		{in: "let i = 0; [1][i];", out: 1},
+		{in: "[1, 2, 3][1 + 1];", out: 3},
+		{in: "let a = [1, 2, 3]; a[0] + a[1] + a[2];", out: 6},
+		{in: "let a = [[1, 2], [3, 4]]; a[1][0];", out: 3},
+	}
+
+	for _, test := range tests {
+		assertInteger(t, evalInput(t, test.in), test.out)
+	}
+}
+
+func Test_ArrayErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "[1, 2, 3][3]", out: "index out of range: 3 with length 3"},
+		{in: "[1, 2, 3][-1]", out: "index out of range: -1 with length 3"},
+		{in: "[][0]", out: "index out of range: 0 with length 0"},
+		{in: `[1]["a"]`, out: "array index must be INTEGER, got STRING"},
+		{in: "1[0]", out: "index operator not supported: INTEGER"},
+		{in: "[1, foo]", out: "identifier not found: foo"},
+	}
+
+	for _, test := range tests {
+		assertError(t, evalInput(t, test.in), test.out)
+	}
+}
+
+func Test_ArrayBuiltins(t *testing.T) {
+	tests := []struct {
+		in  string
+		out any
+	}{
+		{in: `len("")`, out: 0},
+		{in: `len("four")`, out: 4},
+		{in: `len("größe")`, out: 5},
+		{in: `len("\u{1F600}")`, out: 1},
+		{in: `len([1, 2, 3])`, out: 3},
+		{in: `len([])`, out: 0},
+		{in: `first([1, 2, 3])`, out: 1},
+		{in: `first([])`, out: nil},
+		{in: `last([1, 2, 3])`, out: 3},
+		{in: `last([])`, out: nil},
+		{in: `rest([1, 2, 3])`, out: "[2, 3]"},
+		{in: `rest([1])`, out: "[]"},
+		{in: `rest([])`, out: nil},
+		{in: `push([], 1)`, out: "[1]"},
+		{in: `let a = [1]; let b = push(a, 2); a`, out: "[1]"},
+		{in: `let a = [1, 2]; let b = rest(a); a`, out: "[1, 2]"},
+		{in: `len(1)`, out: "error: argument to len not supported, got INTEGER"},
+		{in: `len("one", "two")`, out: "error: wrong number of arguments to len: expected 1, got 2"},
+		{in: `first(1)`, out: "error: argument to first must be ARRAY, got INTEGER"},
+		{in: `push(1, 1)`, out: "error: argument to push must be ARRAY, got INTEGER"},
+		{in: `push([1])`, out: "error: wrong number of arguments to push: expected 2, got 1"},
+		{
+			in: `
+				let map = fun(arr, f) {
+					let iter = fun(arr, acc) {
+						if (len(arr) == 0) { acc } else { iter(rest(arr), push(acc, f(first(arr)))) }
+					};
+					iter(arr, []);
+				};
+				map([1, 2, 3], fun(x) { x * 2 });
+			`,
+			out: "[2, 4, 6]",
+		},
+	}
+
+	for _, test := range tests {
+		obj := evalInput(t, test.in)
+		switch out := test.out.(type) {
+		case int:
+			assertInteger(t, obj, int64(out))
+		case string:
+			assert.Equal(t, out, obj.String(), test.in)
+		case nil:
+			assert.Equal(t, NULL, obj, test.in)
+		}
+	}
+}
+
+func Test_HashLiteral(t *testing.T) {
+	obj := evalInput(t, `
+		let two = "two";
+		{
+			"one": 10 - 9,
+			two: 1 + 1,
+			"thr" + "ee": 6 / 2,
+			4: 4,
+			true: 5,
+			false: 6
+		}
+	`)
+
+	hash, ok := obj.(*object.Hash)
+	require.True(t, ok, fmt.Sprintf("expected hash, got %T (%v)", obj, obj))
+
+	expected := []struct {
+		key   object.Hashable
+		value int64
+	}{
+		{key: &object.String{Value: "one"}, value: 1},
+		{key: &object.String{Value: "two"}, value: 2},
+		{key: &object.String{Value: "three"}, value: 3},
+		{key: &object.Integer{Value: 4}, value: 4},
+		{key: TRUE, value: 5},
+		{key: FALSE, value: 6},
+	}
+
+	require.Equal(t, len(expected), hash.Len())
+	for _, e := range expected {
+		val, ok := hash.Get(e.key)
+		require.True(t, ok, e.key.String())
+		assertInteger(t, val, e.value)
+	}
+	assert.Equal(t, "{one: 1, two: 2, three: 3, 4: 4, true: 5, false: 6}", hash.String())
+}
+
+func Test_HashIndexExpression(t *testing.T) {
+	tests := []struct {
+		in  string
+		out any
+	}{
+		{in: `{"foo": 5}["foo"]`, out: 5},
+		{in: `{"foo": 5}["bar"]`, out: nil},
+		{in: `let key = "foo"; {"foo": 5}[key]`, out: 5},
+		{in: `{}["foo"]`, out: nil},
+		{in: `{5: 5}[5]`, out: 5},
+		{in: `{true: 5}[true]`, out: 5},
+		{in: `{"a": 1, "a": 2}["a"]`, out: 2},
+		{in: `let h = {"a": 1}; let g = put(h, "b", 2); g["b"]`, out: 2},
+		{in: `let h = {"a": 1}; let g = put(h, "a", 2); h["a"]`, out: 1},
+		{in: `len({"a": 1, 2: 3})`, out: 2},
+		{in: `{"a": {"b": 3}}["a"]["b"]`, out: 3},
+	}
+
+	for _, test := range tests {
+		obj := evalInput(t, test.in)
+		if test.out == nil {
+			assert.Equal(t, NULL, obj, test.in)
+		} else {
+			assertInteger(t, obj, int64(test.out.(int)))
+		}
+	}
+}
+
+func Test_HashErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+		pos string
+	}{
+		{in: `{"name": "x"}[fun(x) { x }]`, out: "unusable as hash key: FUNCTION", pos: "1:1"},
+		{in: `{"a": 1, [1]: 2}`, out: "unusable as hash key: ARRAY", pos: "1:10"},
+		{in: `put({}, [], 1)`, out: "unusable as hash key: ARRAY", pos: "1:1"},
+		{in: `put([], 1, 1)`, out: "argument to put must be HASH, got ARRAY", pos: "1:1"},
+	}
+
+	for _, test := range tests {
+		obj := evalInput(t, test.in)
+		assertError(t, obj, test.out)
+		assert.Equal(t, test.pos, obj.(*object.Error).Pos.String())
+	}
+}
@@ -2,49 +2,157 @@ package lexer
 
 import (
 	"bytes"
+	"fmt"
 	"language/tokens"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 const EOF = 0
 
-type symbol byte
+type symbol rune
 
 func (s symbol) String() string {
 	return string(s)
 }
 
 type Lexer struct {
-	input   string
-	pos     int
-	nextPos int
-	symbol  symbol
+	input    string
+	file     string
+	comments bool
+	pos      int
+	nextPos  int
+	line     int
+	column   int
+	symbol   symbol
+	errors   []*Error
 }
 
-func New(input string) *Lexer {
+// Error describes malformed input, the lexer produces an INVALID token
+// for the affected part of the input and continues.
+type Error struct {
+	Pos     tokens.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type Option func(*Lexer)
+
+// WithFile sets the file name reported in the positions of produced tokens.
+func WithFile(name string) Option {
+	return func(l *Lexer) {
+		l.file = name
+	}
+}
+
+// WithComments makes the lexer produce COMMENT tokens, by default comments
+// are skipped like whitespace.
+func WithComments() Option {
+	return func(l *Lexer) {
+		l.comments = true
+	}
+}
+
+func New(input string, opts ...Option) *Lexer {
 	lex := &Lexer{
 		input: input,
+		line:  1,
+	}
+	for _, opt := range opts {
+		opt(lex)
 	}
+
 	lex.readChar()
 	return lex
 }
 
+// Errors returns the errors found in the input read so far.
+func (l *Lexer) Errors() []*Error {
+	return l.errors
+}
+
 func (l *Lexer) NextToken() tokens.Token {
+	for {
+		l.skipWhitespace()
+
+		pos := l.position()
+		token := l.readToken()
+		token.Pos = pos
+		token.End = l.position()
+
+		if token.Type != tokens.COMMENT || l.comments {
+			return token
+		}
+	}
+}
+
+func (l *Lexer) readToken() tokens.Token {
 	var token tokens.Token
-	l.skipWhitespace()
 
 	switch l.symbol {
 	case '+':
-		token = tokens.New(l.symbol.String(), tokens.PLUS)
+		if l.peakNext() == '=' {
+			l.readChar()
+			token = tokens.New("+=", tokens.PLUSASSIGN)
+		} else {
+			token = tokens.New(l.symbol.String(), tokens.PLUS)
+		}
 	case '-':
-		token = tokens.New(l.symbol.String(), tokens.MINUS)
+		if l.peakNext() == '=' {
+			l.readChar()
+			token = tokens.New("-=", tokens.MINUSASSIGN)
+		} else {
+			token = tokens.New(l.symbol.String(), tokens.MINUS)
+		}
 	case '*':
-		token = tokens.New(l.symbol.String(), tokens.MULTIPLY)
+		if l.peakNext() == '*' {
+			l.readChar()
+			token = tokens.New("**", tokens.POWER)
+		} else if l.peakNext() == '=' {
+			l.readChar()
+			token = tokens.New("*=", tokens.MULTIPLYASSIGN)
+		} else {
+			token = tokens.New(l.symbol.String(), tokens.MULTIPLY)
+		}
+	case '%':
+		token = tokens.New(l.symbol.String(), tokens.MODULO)
 	case '/':
-		token = tokens.New(l.symbol.String(), tokens.DIVIDE)
+		switch l.peakNext() {
+		case '/':
+			return l.readLineComment()
+		case '*':
+			return l.readBlockComment()
+		case '=':
+			l.readChar()
+			token = tokens.New("/=", tokens.DIVIDEASSIGN)
+		default:
+			token = tokens.New(l.symbol.String(), tokens.DIVIDE)
+		}
 	case '<':
-		token = tokens.New(l.symbol.String(), tokens.LESS)
+		if l.peakNext() == '=' {
+			l.readChar()
+			token = tokens.New("<=", tokens.LESSEQUAL)
+		} else if l.peakNext() == '<' {
+			l.readChar()
+			token = tokens.New("<<", tokens.SHIFTLEFT)
+		} else {
+			token = tokens.New(l.symbol.String(), tokens.LESS)
+		}
 	case '>':
-		token = tokens.New(l.symbol.String(), tokens.GREATER)
+		if l.peakNext() == '=' {
+			l.readChar()
+			token = tokens.New(">=", tokens.GREATEREQUAL)
+		} else if l.peakNext() == '>' {
+			l.readChar()
+			token = tokens.New(">>", tokens.SHIFTRIGHT)
+		} else {
+			token = tokens.New(l.symbol.String(), tokens.GREATER)
+		}
 	case '(':
 		token = tokens.New(l.symbol.String(), tokens.LPAREN)
 	case ')':
@@ -53,8 +161,32 @@ func (l *Lexer) NextToken() tokens.Token {
 		token = tokens.New(l.symbol.String(), tokens.LBRACE)
 	case '}':
 		token = tokens.New(l.symbol.String(), tokens.RBRACE)
+	case '[':
+		token = tokens.New(l.symbol.String(), tokens.LBRACKET)
+	case ']':
+		token = tokens.New(l.symbol.String(), tokens.RBRACKET)
 	case ',':
 		token = tokens.New(l.symbol.String(), tokens.COMMA)
+	case ':':
+		token = tokens.New(l.symbol.String(), tokens.COLON)
+	case '&':
+		if l.peakNext() == '&' {
+			l.readChar()
+			token = tokens.New("&&", tokens.AND)
+		} else {
+			token = tokens.New(l.symbol.String(), tokens.BITAND)
+		}
+	case '|':
+		if l.peakNext() == '|' {
+			l.readChar()
+			token = tokens.New("||", tokens.OR)
+		} else {
+			token = tokens.New(l.symbol.String(), tokens.BITOR)
+		}
+	case '^':
+		token = tokens.New(l.symbol.String(), tokens.BITXOR)
+	case '~':
+		token = tokens.New(l.symbol.String(), tokens.BITNOT)
 	case '!':
 		if l.peakNext() == '=' {
 			l.readChar()
@@ -71,70 +203,311 @@ func (l *Lexer) NextToken() tokens.Token {
 		}
 	case ';':
 		token = tokens.New(l.symbol.String(), tokens.SEMICOLON)
+	case '"':
+		token = l.readString()
 	case EOF:
-		token = tokens.New(l.symbol.String(), tokens.EOF)
+		token = tokens.New(tokens.EOF, tokens.EOF)
 	default:
-		if l.isChar() {
+		if l.isInvalidEncoding() {
+			token = tokens.New(l.input[l.pos:l.nextPos], tokens.INVALID)
+			break
+		}
+		if l.isLetter() {
 			ident := l.readIdentifier()
 			return tokens.New(ident, tokens.LookupIdentifier(ident))
 		}
-		if l.isNumber() {
-			return tokens.New(l.readInteger(), tokens.INT)
+		if l.isNumber() || l.symbol == '.' && isDigit(l.peakNext()) {
+			return l.readNumber()
 		}
+
+		token = l.unexpectedCharacter()
 	}
 
 	l.readChar()
 	return token
 }
 
+func (l *Lexer) unexpectedCharacter() tokens.Token {
+	l.addError(l.position(), "unexpected character %q", rune(l.symbol))
+	return tokens.New(l.symbol.String(), tokens.INVALID)
+}
+
+func (l *Lexer) addError(pos tokens.Position, format string, args ...any) {
+	l.errors = append(l.errors, &Error{
+		Pos:     pos,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// readLineComment reads a comment up to the end of the line, the newline
+// is not part of the comment.
+func (l *Lexer) readLineComment() tokens.Token {
+	start := l.pos
+	errors := len(l.errors)
+
+	for l.symbol != '\n' && l.symbol != EOF {
+		l.readChar()
+	}
+
+	if len(l.errors) > errors {
+		return tokens.New(l.input[start:l.pos], tokens.INVALID)
+	}
+	return tokens.New(l.input[start:l.pos], tokens.COMMENT)
+}
+
+// readBlockComment reads a comment up to the matching closing delimiter,
+// block comments can be nested.
+func (l *Lexer) readBlockComment() tokens.Token {
+	start := l.position()
+	errors := len(l.errors)
+	depth := 0
+
+	for {
+		switch {
+		case l.symbol == EOF:
+			l.addError(start, "unterminated block comment")
+			return tokens.New(l.input[start.Offset:l.pos], tokens.INVALID)
+		case l.symbol == '/' && l.peakNext() == '*':
+			depth++
+			l.readChar()
+		case l.symbol == '*' && l.peakNext() == '/':
+			depth--
+			l.readChar()
+		}
+		l.readChar()
+
+		if depth == 0 && len(l.errors) > errors {
+			return tokens.New(l.input[start.Offset:l.pos], tokens.INVALID)
+		}
+		if depth == 0 {
+			return tokens.New(l.input[start.Offset:l.pos], tokens.COMMENT)
+		}
+	}
+}
+
+// readString reads a double-quoted string and stops at the closing quote,
+// escape sequences are decoded into the token literal.
+func (l *Lexer) readString() tokens.Token {
+	start := l.position()
+	errors := len(l.errors)
+
+	var str strings.Builder
+	l.readChar() // opening quote
+
+	for l.symbol != '"' {
+		switch l.symbol {
+		case EOF:
+			l.addError(start, "unterminated string literal")
+			return tokens.New(l.input[start.Offset:l.pos], tokens.INVALID)
+		case '\\':
+			l.readEscape(&str)
+		default:
+			str.WriteRune(rune(l.symbol))
+			l.readChar()
+		}
+	}
+
+	if len(l.errors) > errors {
+		return tokens.New(l.input[start.Offset:l.nextPos], tokens.INVALID)
+	}
+	return tokens.New(str.String(), tokens.STRING)
+}
+
+// readEscape decodes the escape sequence starting at the backslash and
+// stops at the first character after it.
+func (l *Lexer) readEscape(str *strings.Builder) {
+	start := l.position()
+	l.readChar() // backslash
+
+	switch l.symbol {
+	case 'n':
+		str.WriteByte('\n')
+	case 't':
+		str.WriteByte('\t')
+	case '"':
+		str.WriteByte('"')
+	case '\\':
+		str.WriteByte('\\')
+	case 'u':
+		l.readUnicodeEscape(str, start)
+		return
+	case EOF:
+		return // reported as unterminated string
+	default:
+		l.addError(start, "invalid escape sequence \\%s in string", l.symbol)
+	}
+
+	l.readChar()
+}
+
+// readUnicodeEscape decodes \u{...} escapes holding 1 to 6 hex digits.
+func (l *Lexer) readUnicodeEscape(str *strings.Builder, start tokens.Position) {
+	l.readChar() // u
+	if l.symbol != '{' {
+		l.addError(start, "invalid unicode escape in string, expected \\u{...}")
+		return
+	}
+	l.readChar()
+
+	var hex bytes.Buffer
+	for l.isHex() {
+		hex.WriteByte(byte(l.symbol))
+		l.readChar()
+	}
+
+	if l.symbol != '}' || hex.Len() == 0 || hex.Len() > 6 {
+		l.addError(start, "invalid unicode escape in string, expected \\u{...}")
+		return
+	}
+	l.readChar()
+
+	code, _ := strconv.ParseUint(hex.String(), 16, 32)
+	if !utf8.ValidRune(rune(code)) {
+		l.addError(start, "invalid unicode code point %s in string", hex.String())
+		return
+	}
+
+	str.WriteRune(rune(code))
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.symbol == ' ' || l.symbol == '\r' || l.symbol == '\t' || l.symbol == '\n' {
 		l.readChar()
 	}
 }
 
+// readChar decodes the next rune of the input, invalid UTF-8 is decoded
+// byte by byte as utf8.RuneError and reported.
 func (l *Lexer) readChar() {
+	if l.nextPos > len(l.input) {
+		return // already at the end of input
+	}
+
+	if l.symbol == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+
+	width := 1
 	if l.nextPos >= len(l.input) {
 		l.symbol = EOF
 	} else {
-		l.symbol = symbol(l.input[l.nextPos])
+		var r rune
+		r, width = utf8.DecodeRuneInString(l.input[l.nextPos:])
+		l.symbol = symbol(r)
 	}
 
 	l.pos = l.nextPos
-	l.nextPos += 1
+	l.nextPos += width
+	l.column += 1
+
+	if l.isInvalidEncoding() {
+		l.addError(l.position(), "invalid UTF-8 encoding")
+	}
 }
 
-func (l *Lexer) peakNext() byte {
+func (l *Lexer) position() tokens.Position {
+	return tokens.Position{
+		File:   l.file,
+		Line:   l.line,
+		Column: l.column,
+		Offset: l.pos,
+	}
+}
+
+func (l *Lexer) peakNext() rune {
 	if l.nextPos >= len(l.input) {
 		return EOF
 	}
 
-	return l.input[l.nextPos]
+	r, _ := utf8.DecodeRuneInString(l.input[l.nextPos:])
+	return r
 }
 
+// readIdentifier reads an identifier, which starts with a letter or an
+// underscore followed by letters, digits and underscores.
 func (l *Lexer) readIdentifier() string {
-	var ident bytes.Buffer
-	for l.isChar() {
-		ident.WriteByte(byte(l.symbol))
+	start := l.pos
+	for l.isLetter() || unicode.IsDigit(rune(l.symbol)) {
 		l.readChar()
 	}
-	return ident.String()
+	return l.input[start:l.pos]
 }
 
-func (l *Lexer) readInteger() string {
-	var integer bytes.Buffer
-	for l.isNumber() {
-		integer.WriteByte(byte(l.symbol))
+// readNumber reads an integer or a float, floats have a fraction, an
+// exponent or both, e.g. 3.14, .5 or 1e-9. Integers can be written in hex,
+// octal and binary, and digits can be separated by underscores.
+func (l *Lexer) readNumber() tokens.Token {
+	start := l.pos
+	var tokenType tokens.TokenType = tokens.INT
+
+	// digits of hex, octal and binary literals are validated by the parser
+	if l.symbol == '0' && strings.ContainsRune("xXoObB", l.peakNext()) {
 		l.readChar()
+		l.readChar()
+		for l.isLetter() || l.isNumber() {
+			l.readChar()
+		}
+		return tokens.New(l.input[start:l.pos], tokenType)
 	}
 
-	return integer.String()
+	l.readDigits()
+	if l.symbol == '.' && isDigit(l.peakNext()) {
+		tokenType = tokens.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+
+	if l.isExponent() {
+		tokenType = tokens.FLOAT
+		l.readChar()
+		if l.symbol == '+' || l.symbol == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+
+	return tokens.New(l.input[start:l.pos], tokenType)
 }
 
-func (l *Lexer) isChar() bool {
-	return l.symbol >= 'a' && l.symbol <= 'z' || l.symbol >= 'A' && l.symbol <= 'Z'
+func (l *Lexer) readDigits() {
+	for l.isNumber() || l.symbol == '_' {
+		l.readChar()
+	}
+}
+
+// isExponent reports whether the current symbol starts the exponent of a
+// float, which is e or E followed by digits with an optional sign.
+func (l *Lexer) isExponent() bool {
+	if l.symbol != 'e' && l.symbol != 'E' {
+		return false
+	}
+
+	next := l.input[l.nextPos:]
+	if len(next) > 0 && (next[0] == '+' || next[0] == '-') {
+		next = next[1:]
+	}
+	return len(next) > 0 && isDigit(rune(next[0]))
+}
+
+func (l *Lexer) isLetter() bool {
+	return unicode.IsLetter(rune(l.symbol)) || l.symbol == '_'
+}
+
+// isInvalidEncoding reports whether the current symbol is a byte that is
+// not valid UTF-8, as opposed to an encoded U+FFFD.
+func (l *Lexer) isInvalidEncoding() bool {
+	return l.symbol == utf8.RuneError && l.nextPos-l.pos == 1
 }
 
 func (l *Lexer) isNumber() bool {
-	return l.symbol >= '0' && l.symbol <= '9'
+	return isDigit(rune(l.symbol))
+}
+
+func (l *Lexer) isHex() bool {
+	return l.isNumber() || l.symbol >= 'a' && l.symbol <= 'f' || l.symbol >= 'A' && l.symbol <= 'F'
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
 }
@@ -6,12 +6,14 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// readAllTokens returns the tokens without positions, which are covered separately.
 func readAllTokens(lexer *Lexer) []tokens.Token {
 	all := make([]tokens.Token, 0)
 	for token := lexer.NextToken(); token.Type != tokens.EOF; token = lexer.NextToken() {
-		all = append(all, token)
+		all = append(all, tokens.New(token.Literal, token.Type))
 	}
 	return all
 }
@@ -27,6 +29,66 @@ func TestNextToken(t *testing.T) {
 			{Literal: "+", Type: tokens.PLUS},
 			{Literal: "2", Type: tokens.INT},
 		},
+	}, {
+		in: "a && b || !c",
+		out: []tokens.Token{
+			{Literal: "a", Type: tokens.IDENTIFIER},
+			{Literal: "&&", Type: tokens.AND},
+			{Literal: "b", Type: tokens.IDENTIFIER},
+			{Literal: "||", Type: tokens.OR},
+			{Literal: "!", Type: tokens.BANG},
+			{Literal: "c", Type: tokens.IDENTIFIER},
+		},
+	}, {
+		in: "a <= b >= c % d ** e * f",
+		out: []tokens.Token{
+			{Literal: "a", Type: tokens.IDENTIFIER},
+			{Literal: "<=", Type: tokens.LESSEQUAL},
+			{Literal: "b", Type: tokens.IDENTIFIER},
+			{Literal: ">=", Type: tokens.GREATEREQUAL},
+			{Literal: "c", Type: tokens.IDENTIFIER},
+			{Literal: "%", Type: tokens.MODULO},
+			{Literal: "d", Type: tokens.IDENTIFIER},
+			{Literal: "**", Type: tokens.POWER},
+			{Literal: "e", Type: tokens.IDENTIFIER},
+			{Literal: "*", Type: tokens.MULTIPLY},
+			{Literal: "f", Type: tokens.IDENTIFIER},
+		},
+	}, {
+		in: "x = 1 += 2 -= 3 *= 4 /= 5 == 6",
+		out: []tokens.Token{
+			{Literal: "x", Type: tokens.IDENTIFIER},
+			{Literal: "=", Type: tokens.ASSIGN},
+			{Literal: "1", Type: tokens.INT},
+			{Literal: "+=", Type: tokens.PLUSASSIGN},
+			{Literal: "2", Type: tokens.INT},
+			{Literal: "-=", Type: tokens.MINUSASSIGN},
+			{Literal: "3", Type: tokens.INT},
+			{Literal: "*=", Type: tokens.MULTIPLYASSIGN},
+			{Literal: "4", Type: tokens.INT},
+			{Literal: "/=", Type: tokens.DIVIDEASSIGN},
+			{Literal: "5", Type: tokens.INT},
+			{Literal: "==", Type: tokens.EQUAL},
+			{Literal: "6", Type: tokens.INT},
+		},
+	}, {
+		in: "a & b | c ^ ~d << 1 >> 2 < e",
+		out: []tokens.Token{
+			{Literal: "a", Type: tokens.IDENTIFIER},
+			{Literal: "&", Type: tokens.BITAND},
+			{Literal: "b", Type: tokens.IDENTIFIER},
+			{Literal: "|", Type: tokens.BITOR},
+			{Literal: "c", Type: tokens.IDENTIFIER},
+			{Literal: "^", Type: tokens.BITXOR},
+			{Literal: "~", Type: tokens.BITNOT},
+			{Literal: "d", Type: tokens.IDENTIFIER},
+			{Literal: "<<", Type: tokens.SHIFTLEFT},
+			{Literal: "1", Type: tokens.INT},
+			{Literal: ">>", Type: tokens.SHIFTRIGHT},
+			{Literal: "2", Type: tokens.INT},
+			{Literal: "<", Type: tokens.LESS},
+			{Literal: "e", Type: tokens.IDENTIFIER},
+		},
 	}, {
 		in: "1 + 2 * 3 / (let foo = 0)",
 		out: []tokens.Token{
@@ -67,6 +129,44 @@ func TestNextToken(t *testing.T) {
 			{Literal: "y", Type: tokens.IDENTIFIER},
 			{Literal: "}", Type: tokens.RBRACE},
 		},
+	}, {
+		in: "if (x > 1) { 10 } else { 20 }",
+		out: []tokens.Token{
+			{Literal: "if", Type: tokens.IF},
+			{Literal: "(", Type: tokens.LPAREN},
+			{Literal: "x", Type: tokens.IDENTIFIER},
+			{Literal: ">", Type: tokens.GREATER},
+			{Literal: "1", Type: tokens.INT},
+			{Literal: ")", Type: tokens.RPAREN},
+			{Literal: "{", Type: tokens.LBRACE},
+			{Literal: "10", Type: tokens.INT},
+			{Literal: "}", Type: tokens.RBRACE},
+			{Literal: "else", Type: tokens.ELSE},
+			{Literal: "{", Type: tokens.LBRACE},
+			{Literal: "20", Type: tokens.INT},
+			{Literal: "}", Type: tokens.RBRACE},
+		},
+	}, {
+		in: "[1, 2][0]",
+		out: []tokens.Token{
+			{Literal: "[", Type: tokens.LBRACKET},
+			{Literal: "1", Type: tokens.INT},
+			{Literal: ",", Type: tokens.COMMA},
+			{Literal: "2", Type: tokens.INT},
+			{Literal: "]", Type: tokens.RBRACKET},
+			{Literal: "[", Type: tokens.LBRACKET},
+			{Literal: "0", Type: tokens.INT},
+			{Literal: "]", Type: tokens.RBRACKET},
+		},
+	}, {
+		in: `{"a": 1}`,
+		out: []tokens.Token{
+			{Literal: "{", Type: tokens.LBRACE},
+			{Literal: "a", Type: tokens.STRING},
+			{Literal: ":", Type: tokens.COLON},
+			{Literal: "1", Type: tokens.INT},
+			{Literal: "}", Type: tokens.RBRACE},
+		},
 	}}
 
 	for i, test := range tests {
@@ -75,3 +175,321 @@ func TestNextToken(t *testing.T) {
 		assert.Equal(t, test.out, all, fmt.Sprintf("test number: %d failed", i))
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let foo = 10;\n  foo != 2\n"
+
+	tests := []struct {
+		literal string
+		pos     tokens.Position
+		end     tokens.Position
+	}{
+		{literal: "let", pos: tokens.Position{Line: 1, Column: 1, Offset: 0}, end: tokens.Position{Line: 1, Column: 4, Offset: 3}},
+		{literal: "foo", pos: tokens.Position{Line: 1, Column: 5, Offset: 4}, end: tokens.Position{Line: 1, Column: 8, Offset: 7}},
+		{literal: "=", pos: tokens.Position{Line: 1, Column: 9, Offset: 8}, end: tokens.Position{Line: 1, Column: 10, Offset: 9}},
+		{literal: "10", pos: tokens.Position{Line: 1, Column: 11, Offset: 10}, end: tokens.Position{Line: 1, Column: 13, Offset: 12}},
+		{literal: ";", pos: tokens.Position{Line: 1, Column: 13, Offset: 12}, end: tokens.Position{Line: 1, Column: 14, Offset: 13}},
+		{literal: "foo", pos: tokens.Position{Line: 2, Column: 3, Offset: 16}, end: tokens.Position{Line: 2, Column: 6, Offset: 19}},
+		{literal: "!=", pos: tokens.Position{Line: 2, Column: 7, Offset: 20}, end: tokens.Position{Line: 2, Column: 9, Offset: 22}},
+		{literal: "2", pos: tokens.Position{Line: 2, Column: 10, Offset: 23}, end: tokens.Position{Line: 2, Column: 11, Offset: 24}},
+		{literal: "", pos: tokens.Position{Line: 3, Column: 1, Offset: 25}, end: tokens.Position{Line: 3, Column: 1, Offset: 25}},
+	}
+
+	lexer := New(input)
+	for i, test := range tests {
+		token := lexer.NextToken()
+		assert.Equal(t, test.literal, token.Literal, fmt.Sprintf("test number: %d failed", i))
+		assert.Equal(t, test.pos, token.Pos, fmt.Sprintf("test number: %d failed", i))
+		assert.Equal(t, test.end, token.End, fmt.Sprintf("test number: %d failed", i))
+	}
+
+	// reading past the end keeps returning EOF at the same position
+	assert.Equal(t, tests[len(tests)-1].pos, lexer.NextToken().Pos)
+}
+
+func TestTokenPositionFile(t *testing.T) {
+	lexer := New("x", WithFile("main.lang"))
+	token := lexer.NextToken()
+
+	assert.Equal(t, tokens.Position{File: "main.lang", Line: 1, Column: 1, Offset: 0}, token.Pos)
+	assert.Equal(t, "main.lang:1:1", token.Pos.String())
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []tokens.Token
+	}{{
+		in: `"foo" + "bar baz"`,
+		out: []tokens.Token{
+			{Literal: "foo", Type: tokens.STRING},
+			{Literal: "+", Type: tokens.PLUS},
+			{Literal: "bar baz", Type: tokens.STRING},
+		},
+	}, {
+		in: `""`,
+		out: []tokens.Token{
+			{Literal: "", Type: tokens.STRING},
+		},
+	}, {
+		in: `"a\nb\tc \"q\" \\ \u{41}\u{1F600}"`,
+		out: []tokens.Token{
+			{Literal: "a\nb\tc \"q\" \\ A\U0001F600", Type: tokens.STRING},
+		},
+	}, {
+		in: `let s = "x";`,
+		out: []tokens.Token{
+			{Literal: "let", Type: tokens.LET},
+			{Literal: "s", Type: tokens.IDENTIFIER},
+			{Literal: "=", Type: tokens.ASSIGN},
+			{Literal: "x", Type: tokens.STRING},
+			{Literal: ";", Type: tokens.SEMICOLON},
+		},
+	}}
+
+	for i, test := range tests {
+		lexer := New(test.in)
+		all := readAllTokens(lexer)
+		assert.Equal(t, test.out, all, fmt.Sprintf("test number: %d failed", i))
+		assert.Len(t, lexer.Errors(), 0)
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		in      string
+		token   tokens.Token
+		message string
+		pos     string
+	}{
+		{in: `1 "abc`, token: tokens.New(`"abc`, tokens.INVALID), message: "unterminated string literal", pos: "1:3"},
+		{in: `"a\qb"`, token: tokens.New(`"a\qb"`, tokens.INVALID), message: `invalid escape sequence \q in string`, pos: "1:3"},
+		{in: `"\u41"`, token: tokens.New(`"\u41"`, tokens.INVALID), message: `invalid unicode escape in string, expected \u{...}`, pos: "1:2"},
+		{in: `"\u{}"`, token: tokens.New(`"\u{}"`, tokens.INVALID), message: `invalid unicode escape in string, expected \u{...}`, pos: "1:2"},
+		{in: `"\u{D800}"`, token: tokens.New(`"\u{D800}"`, tokens.INVALID), message: "invalid unicode code point D800 in string", pos: "1:2"},
+		{in: "\"abc\\", token: tokens.New("\"abc\\", tokens.INVALID), message: "unterminated string literal", pos: "1:1"},
+	}
+
+	for i, test := range tests {
+		lexer := New(test.in)
+		all := readAllTokens(lexer)
+		assert.Equal(t, test.token, all[len(all)-1], fmt.Sprintf("test number: %d failed", i))
+
+		require.Len(t, lexer.Errors(), 1)
+		assert.Equal(t, test.message, lexer.Errors()[0].Error())
+		assert.Equal(t, test.pos, lexer.Errors()[0].Pos.String())
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading
+let x = 1; // trailing
+/* block /* nested */ still comment */ x / 2;
+/**/`
+
+	expected := []tokens.Token{
+		{Literal: "// leading", Type: tokens.COMMENT},
+		{Literal: "let", Type: tokens.LET},
+		{Literal: "x", Type: tokens.IDENTIFIER},
+		{Literal: "=", Type: tokens.ASSIGN},
+		{Literal: "1", Type: tokens.INT},
+		{Literal: ";", Type: tokens.SEMICOLON},
+		{Literal: "// trailing", Type: tokens.COMMENT},
+		{Literal: "/* block /* nested */ still comment */", Type: tokens.COMMENT},
+		{Literal: "x", Type: tokens.IDENTIFIER},
+		{Literal: "/", Type: tokens.DIVIDE},
+		{Literal: "2", Type: tokens.INT},
+		{Literal: ";", Type: tokens.SEMICOLON},
+		{Literal: "/**/", Type: tokens.COMMENT},
+	}
+
+	lexer := New(input, WithComments())
+	assert.Equal(t, expected, readAllTokens(lexer))
+	assert.Len(t, lexer.Errors(), 0)
+
+	var withoutComments []tokens.Token
+	for _, token := range expected {
+		if token.Type != tokens.COMMENT {
+			withoutComments = append(withoutComments, token)
+		}
+	}
+	assert.Equal(t, withoutComments, readAllTokens(New(input)))
+}
+
+func TestCommentPositions(t *testing.T) {
+	lexer := New("1 /* a\nb */ 2", WithComments())
+	lexer.NextToken()
+
+	comment := lexer.NextToken()
+	assert.Equal(t, "1:3", comment.Pos.String())
+	assert.Equal(t, "2:5", comment.End.String())
+
+	// the positions after a skipped comment are not affected
+	lexer = New("1 /* a\nb */ 2")
+	lexer.NextToken()
+	assert.Equal(t, "2:6", lexer.NextToken().Pos.String())
+}
+
+func TestCommentErrors(t *testing.T) {
+	tests := []struct {
+		in    string
+		token tokens.Token
+		pos   string
+	}{
+		{in: "1 /* abc", token: tokens.New("/* abc", tokens.INVALID), pos: "1:3"},
+		{in: "/* a /* b */", token: tokens.New("/* a /* b */", tokens.INVALID), pos: "1:1"},
+		{in: "/*/", token: tokens.New("/*/", tokens.INVALID), pos: "1:1"},
+	}
+
+	for i, test := range tests {
+		lexer := New(test.in)
+		all := readAllTokens(lexer)
+		assert.Equal(t, test.token, all[len(all)-1], fmt.Sprintf("test number: %d failed", i))
+
+		require.Len(t, lexer.Errors(), 1)
+		assert.Equal(t, "unterminated block comment", lexer.Errors()[0].Error())
+		assert.Equal(t, test.pos, lexer.Errors()[0].Pos.String())
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := "let größe = \"😀 ü\";\n_x1 + größe2;"
+	lexer := New(input)
+
+	expected := []struct {
+		token tokens.Token
+		pos   string
+	}{
+		{token: tokens.New("let", tokens.LET), pos: "1:1"},
+		{token: tokens.New("größe", tokens.IDENTIFIER), pos: "1:5"},
+		{token: tokens.New("=", tokens.ASSIGN), pos: "1:11"},
+		{token: tokens.New("😀 ü", tokens.STRING), pos: "1:13"},
+		{token: tokens.New(";", tokens.SEMICOLON), pos: "1:18"},
+		{token: tokens.New("_x1", tokens.IDENTIFIER), pos: "2:1"},
+		{token: tokens.New("+", tokens.PLUS), pos: "2:5"},
+		{token: tokens.New("größe2", tokens.IDENTIFIER), pos: "2:7"},
+		{token: tokens.New(";", tokens.SEMICOLON), pos: "2:13"},
+	}
+
+	for _, e := range expected {
+		token := lexer.NextToken()
+		assert.Equal(t, e.token, tokens.New(token.Literal, token.Type))
+		assert.Equal(t, e.pos, token.Pos.String(), e.token.Literal)
+	}
+
+	// offsets are kept in bytes, so they can be used to slice the input
+	assert.Equal(t, len(input), lexer.NextToken().Pos.Offset)
+	assert.Len(t, lexer.Errors(), 0)
+}
+
+func TestInvalidEncoding(t *testing.T) {
+	tests := []struct {
+		in    string
+		token tokens.Token
+		pos   string
+	}{
+		{in: "1 + \xff", token: tokens.New("\xff", tokens.INVALID), pos: "1:5"},
+		{in: "\"a\xffb\"", token: tokens.New("\"a\xffb\"", tokens.INVALID), pos: "1:3"},
+		{in: "// ü\xff", token: tokens.New("// ü\xff", tokens.INVALID), pos: "1:5"},
+	}
+
+	for i, test := range tests {
+		lexer := New(test.in)
+		all := readAllTokens(lexer)
+		assert.Equal(t, test.token, all[len(all)-1], fmt.Sprintf("test number: %d failed", i))
+
+		require.Len(t, lexer.Errors(), 1)
+		assert.Equal(t, "invalid UTF-8 encoding", lexer.Errors()[0].Error())
+		assert.Equal(t, test.pos, lexer.Errors()[0].Pos.String())
+	}
+
+	// an encoded replacement character is valid input
+	lexer := New("\"\uFFFD\"")
+	assert.Equal(t, []tokens.Token{tokens.New("\uFFFD", tokens.STRING)}, readAllTokens(lexer))
+	assert.Len(t, lexer.Errors(), 0)
+}
+
+func TestUnknownCharacters(t *testing.T) {
+	lexer := New("a @ b\n#$ ü€")
+
+	expected := []tokens.Token{
+		tokens.New("a", tokens.IDENTIFIER),
+		tokens.New("@", tokens.INVALID),
+		tokens.New("b", tokens.IDENTIFIER),
+		tokens.New("#", tokens.INVALID),
+		tokens.New("$", tokens.INVALID),
+		tokens.New("ü", tokens.IDENTIFIER),
+		tokens.New("€", tokens.INVALID),
+	}
+	assert.Equal(t, expected, readAllTokens(lexer))
+
+	errors := make([]string, len(lexer.Errors()))
+	for i, err := range lexer.Errors() {
+		errors[i] = fmt.Sprintf("%s: %s", err.Pos, err)
+	}
+	assert.Equal(t, []string{
+		"1:3: unexpected character '@'",
+		"2:1: unexpected character '#'",
+		"2:2: unexpected character '$'",
+		"2:5: unexpected character '€'",
+	}, errors)
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []tokens.Token
+	}{
+		{in: "3.14", out: []tokens.Token{tokens.New("3.14", tokens.FLOAT)}},
+		{in: ".5", out: []tokens.Token{tokens.New(".5", tokens.FLOAT)}},
+		{in: "1e-9", out: []tokens.Token{tokens.New("1e-9", tokens.FLOAT)}},
+		{in: "2.5E+3", out: []tokens.Token{tokens.New("2.5E+3", tokens.FLOAT)}},
+		{in: "10e2", out: []tokens.Token{tokens.New("10e2", tokens.FLOAT)}},
+		{in: "42", out: []tokens.Token{tokens.New("42", tokens.INT)}},
+		{in: "0xFF", out: []tokens.Token{tokens.New("0xFF", tokens.INT)}},
+		{in: "0o755", out: []tokens.Token{tokens.New("0o755", tokens.INT)}},
+		{in: "0b1010", out: []tokens.Token{tokens.New("0b1010", tokens.INT)}},
+		{in: "0b102", out: []tokens.Token{tokens.New("0b102", tokens.INT)}},
+		{in: "1_000_000", out: []tokens.Token{tokens.New("1_000_000", tokens.INT)}},
+		{in: "1_000.000_1", out: []tokens.Token{tokens.New("1_000.000_1", tokens.FLOAT)}},
+		{
+			in: "0x1f+0b1",
+			out: []tokens.Token{
+				tokens.New("0x1f", tokens.INT),
+				tokens.New("+", tokens.PLUS),
+				tokens.New("0b1", tokens.INT),
+			},
+		},
+		{
+			in: "1.5*.5-1e2",
+			out: []tokens.Token{
+				tokens.New("1.5", tokens.FLOAT),
+				tokens.New("*", tokens.MULTIPLY),
+				tokens.New(".5", tokens.FLOAT),
+				tokens.New("-", tokens.MINUS),
+				tokens.New("1e2", tokens.FLOAT),
+			},
+		},
+		{
+			// an exponent needs digits, otherwise the e starts an identifier
+			in: "1e 2ex",
+			out: []tokens.Token{
+				tokens.New("1", tokens.INT),
+				tokens.New("e", tokens.IDENTIFIER),
+				tokens.New("2", tokens.INT),
+				tokens.New("ex", tokens.IDENTIFIER),
+			},
+		},
+		{
+			in: "1.",
+			out: []tokens.Token{
+				tokens.New("1", tokens.INT),
+				tokens.New(".", tokens.INVALID),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.out, readAllTokens(New(test.in)), test.in)
+	}
+}
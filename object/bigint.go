@@ -0,0 +1,144 @@
+package object
+
+import (
+	"language/tokens"
+	"math"
+	"math/big"
+)
+
+// maxBigIntegerBits limits the size of the results of ** and <<, which
+// could otherwise exhaust the memory in a single operation. The limit is
+// checked against an estimate of the size before the result is computed.
+const maxBigIntegerBits = 1 << 20
+
+// IntegerFromBig returns an Integer when the value fits in int64 and a
+// BigInteger otherwise.
+func IntegerFromBig(v *big.Int) Object {
+	if v.IsInt64() {
+		return &Integer{Value: v.Int64()}
+	}
+	return &BigInteger{Value: v}
+}
+
+func toBig(obj Object) *big.Int {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value)
+	case *BigInteger:
+		return obj.Value
+	}
+	return new(big.Int)
+}
+
+// overflows reports whether applying the operator to the int64 operands
+// wraps around.
+func overflows(operator string, a, b int64) bool {
+	switch operator {
+	case tokens.PLUS:
+		sum := a + b
+		return a > 0 && b > 0 && sum < 0 || a < 0 && b < 0 && sum >= 0
+	case tokens.MINUS:
+		diff := a - b
+		return a >= 0 && b < 0 && diff < 0 || a < 0 && b > 0 && diff >= 0
+	case tokens.MULTIPLY:
+		if a == 0 || b == 0 {
+			return false
+		}
+		product := a * b
+		return product/b != a || a == -1 && b == math.MinInt64 || b == -1 && a == math.MinInt64
+	case tokens.DIVIDE:
+		return a == math.MinInt64 && b == -1
+	case tokens.POWER:
+		_, ok := power(a, b)
+		return b >= 0 && !ok
+	case tokens.SHIFTLEFT:
+		return b >= 0 && a<<b>>b != a
+	}
+	return false
+}
+
+// power raises the base to a non-negative exponent by squaring, ok is false
+// when an intermediate result wraps around.
+func power(base, exp int64) (result int64, ok bool) {
+	result, ok = 1, true
+	for exp > 0 {
+		if exp&1 == 1 {
+			ok = ok && !overflows(tokens.MULTIPLY, result, base)
+			result *= base
+		}
+		exp >>= 1
+		if exp > 0 {
+			ok = ok && !overflows(tokens.MULTIPLY, base, base)
+			base *= base
+		}
+	}
+	return result, ok
+}
+
+// bigInfix applies the operator to integers of which at least one is a
+// BigInteger, or to integers whose int64 result overflows.
+func bigInfix(operator string, left, right Object) Object {
+	l, r := toBig(left), toBig(right)
+
+	switch operator {
+	case tokens.PLUS:
+		return IntegerFromBig(new(big.Int).Add(l, r))
+	case tokens.MINUS:
+		return IntegerFromBig(new(big.Int).Sub(l, r))
+	case tokens.MULTIPLY:
+		return IntegerFromBig(new(big.Int).Mul(l, r))
+	case tokens.DIVIDE:
+		if r.Sign() == 0 {
+			return newError("division by zero: %s / %s", left, right)
+		}
+		// truncated like the division of int64 values
+		return IntegerFromBig(new(big.Int).Quo(l, r))
+	case tokens.MODULO:
+		if r.Sign() == 0 {
+			return newError("division by zero: %s %% %s", left, right)
+		}
+		return IntegerFromBig(new(big.Int).Rem(l, r))
+	case tokens.POWER:
+		if r.Sign() < 0 {
+			return &Float{Value: math.Pow(toFloat(left), toFloat(right))}
+		}
+		// the result has at least exp * (bits of base - 1) bits, a base
+		// of 0, 1 or -1 keeps its size
+		if !r.IsInt64() || l.BitLen() > 1 && r.Int64() > maxBigIntegerBits/int64(l.BitLen()-1) {
+			return newError("exponent too large: %s", right)
+		}
+		return IntegerFromBig(new(big.Int).Exp(l, r, nil))
+	case tokens.BITAND:
+		return IntegerFromBig(new(big.Int).And(l, r))
+	case tokens.BITOR:
+		return IntegerFromBig(new(big.Int).Or(l, r))
+	case tokens.BITXOR:
+		return IntegerFromBig(new(big.Int).Xor(l, r))
+	case tokens.SHIFTLEFT, tokens.SHIFTRIGHT:
+		if r.Sign() < 0 {
+			return newError("negative shift count: %s", right)
+		}
+		if !r.IsInt64() || operator == tokens.SHIFTLEFT && l.Sign() != 0 && r.Int64() > maxBigIntegerBits {
+			return newError("shift count too large: %s", right)
+		}
+		if operator == tokens.SHIFTLEFT {
+			return IntegerFromBig(new(big.Int).Lsh(l, uint(r.Int64())))
+		}
+		// rounds towards negative infinity like the shift of int64 values
+		return IntegerFromBig(new(big.Int).Rsh(l, uint(r.Int64())))
+	case tokens.LESS:
+		return NativeBoolean(l.Cmp(r) < 0)
+	case tokens.GREATER:
+		return NativeBoolean(l.Cmp(r) > 0)
+	case tokens.LESSEQUAL:
+		return NativeBoolean(l.Cmp(r) <= 0)
+	case tokens.GREATEREQUAL:
+		return NativeBoolean(l.Cmp(r) >= 0)
+	case tokens.EQUAL:
+		return NativeBoolean(l.Cmp(r) == 0)
+	case tokens.NOTEQUAL:
+		return NativeBoolean(l.Cmp(r) != 0)
+	}
+
+	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
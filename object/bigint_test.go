@@ -0,0 +1,78 @@
+package object
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func Test_Overflows(t *testing.T) {
+	tests := []struct {
+		operator string
+		a, b     int64
+		out      bool
+	}{
+		{operator: "+", a: math.MaxInt64, b: 1, out: true},
+		{operator: "+", a: math.MinInt64, b: -1, out: true},
+		{operator: "+", a: math.MaxInt64, b: -1, out: false},
+		{operator: "-", a: math.MinInt64, b: 1, out: true},
+		{operator: "-", a: 0, b: math.MinInt64, out: true},
+		{operator: "-", a: -1, b: math.MinInt64, out: false},
+		{operator: "*", a: math.MaxInt64, b: 2, out: true},
+		{operator: "*", a: -1, b: math.MinInt64, out: true},
+		{operator: "*", a: math.MinInt64, b: 1, out: false},
+		{operator: "*", a: 1 << 31, b: 1 << 31, out: false},
+		{operator: "*", a: 1 << 32, b: 1 << 31, out: true},
+		{operator: "/", a: math.MinInt64, b: -1, out: true},
+		{operator: "/", a: math.MinInt64, b: 1, out: false},
+		{operator: "%", a: math.MinInt64, b: -1, out: false},
+		{operator: "**", a: 2, b: 62, out: false},
+		{operator: "**", a: 2, b: 63, out: true},
+		{operator: "**", a: -2, b: 63, out: false},
+		{operator: "**", a: 3, b: 40, out: true},
+		{operator: "**", a: 1, b: math.MaxInt64, out: false},
+		{operator: "**", a: 2, b: -1, out: false},
+		{operator: "<<", a: 1, b: 62, out: false},
+		{operator: "<<", a: 1, b: 63, out: true},
+		{operator: "<<", a: -1, b: 63, out: false},
+		{operator: "<<", a: 0, b: 100, out: false},
+		{operator: "<<", a: 1, b: -1, out: false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.out, overflows(test.operator, test.a, test.b), "%d %s %d", test.a, test.operator, test.b)
+	}
+}
+
+func Test_BigIntegers(t *testing.T) {
+	rt := &Runtime{BigIntegers: true}
+	max := &Integer{Value: math.MaxInt64}
+	one := &Integer{Value: 1}
+
+	sum := rt.Infix("+", max, one)
+	assert.IsType(t, &BigInteger{}, sum)
+	assert.Equal(t, "9223372036854775808", sum.String())
+	assert.Equal(t, INTEGER, string(sum.Type()))
+
+	// results that fit are turned back into int64 integers
+	assert.Equal(t, max, rt.Infix("-", sum, one))
+	assert.Equal(t, &Integer{Value: math.MinInt64}, rt.Prefix("-", sum))
+
+	assert.Equal(t, TrueValue, rt.Infix(">", sum, max))
+	assert.Equal(t, TrueValue, rt.Infix("==", sum, IntegerFromBig(new(big.Int).Add(toBig(max), big.NewInt(1)))))
+	assert.Equal(t, newError("division by zero: 9223372036854775808 / 0"), rt.Infix("/", sum, &Integer{Value: 0}))
+	assert.Equal(t, (&BigInteger{Value: toBig(sum)}).HashKey(), sum.(Hashable).HashKey())
+
+	// results that would exhaust the memory are rejected before computing them
+	two := &Integer{Value: 2}
+	assert.Equal(t, newError("exponent too large: 10000000000"), rt.Infix("**", two, &Integer{Value: 10000000000}))
+	assert.Equal(t, newError("shift count too large: 9223372036854775807"), rt.Infix("<<", one, max))
+	assert.Equal(t, one, rt.Infix("**", &Integer{Value: 1}, &Integer{Value: 10000000000}))
+	assert.Equal(t, &Integer{Value: 0}, rt.Infix("<<", &Integer{Value: 0}, max))
+}
+
+func Test_IntegerOverflowWraps(t *testing.T) {
+	rt := NewRuntime()
+	assert.Equal(t, &Integer{Value: math.MinInt64}, rt.Infix("+", &Integer{Value: math.MaxInt64}, &Integer{Value: 1}))
+}
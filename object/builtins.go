@@ -0,0 +1,163 @@
+package object
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// BuiltinFunction returns nil when it has no value to return, the caller
+// then treats the result as null.
+type BuiltinFunction func(rt *Runtime, args ...Object) Object
+
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType {
+	return BUILTIN
+}
+
+func (b *Builtin) String() string {
+	return fmt.Sprintf("builtin %s", b.Name)
+}
+
+// Builtins are kept in a fixed order, so they can be referred to by index.
+var Builtins = []*Builtin{
+	{Name: "puts", Fn: puts},
+	{Name: "len", Fn: length},
+	{Name: "first", Fn: first},
+	{Name: "last", Fn: last},
+	{Name: "rest", Fn: rest},
+	{Name: "push", Fn: push},
+	{Name: "put", Fn: put},
+}
+
+func LookupBuiltin(name string) (*Builtin, bool) {
+	for _, b := range Builtins {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+func puts(rt *Runtime, args ...Object) Object {
+	for _, arg := range args {
+		fmt.Fprintln(rt.Stdout, arg)
+	}
+	return nil
+}
+
+func length(_ *Runtime, args ...Object) Object {
+	if err := checkArgs("len", args, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *String:
+		// strings are counted in characters, like the lexer counts columns
+		return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+	case *Array:
+		return &Integer{Value: int64(len(arg.Elements))}
+	case *Hash:
+		return &Integer{Value: int64(arg.Len())}
+	}
+
+	return newError("argument to len not supported, got %s", args[0].Type())
+}
+
+func first(_ *Runtime, args ...Object) Object {
+	array, err := arrayArg("first", args, 1)
+	if err != nil {
+		return err
+	}
+
+	if len(array.Elements) == 0 {
+		return nil
+	}
+	return array.Elements[0]
+}
+
+func last(_ *Runtime, args ...Object) Object {
+	array, err := arrayArg("last", args, 1)
+	if err != nil {
+		return err
+	}
+
+	if len(array.Elements) == 0 {
+		return nil
+	}
+	return array.Elements[len(array.Elements)-1]
+}
+
+func rest(_ *Runtime, args ...Object) Object {
+	array, err := arrayArg("rest", args, 1)
+	if err != nil {
+		return err
+	}
+
+	if len(array.Elements) == 0 {
+		return nil
+	}
+
+	elements := make([]Object, len(array.Elements)-1)
+	copy(elements, array.Elements[1:])
+	return &Array{Elements: elements}
+}
+
+func push(_ *Runtime, args ...Object) Object {
+	array, err := arrayArg("push", args, 2)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]Object, len(array.Elements), len(array.Elements)+1)
+	copy(elements, array.Elements)
+	return &Array{Elements: append(elements, args[1])}
+}
+
+func put(_ *Runtime, args ...Object) Object {
+	if err := checkArgs("put", args, 3); err != nil {
+		return err
+	}
+
+	hash, ok := args[0].(*Hash)
+	if !ok {
+		return newError("argument to put must be HASH, got %s", args[0].Type())
+	}
+
+	key, ok := args[1].(Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	hash = hash.Copy()
+	hash.Set(key, args[2])
+	return hash
+}
+
+func checkArgs(name string, args []Object, expected int) *Error {
+	if len(args) != expected {
+		return newError("wrong number of arguments to %s: expected %d, got %d", name, expected, len(args))
+	}
+	return nil
+}
+
+// arrayArg checks the number of arguments and returns the first one, which
+// must be an array.
+func arrayArg(name string, args []Object, expected int) (*Array, *Error) {
+	if err := checkArgs(name, args, expected); err != nil {
+		return nil, err
+	}
+
+	array, ok := args[0].(*Array)
+	if !ok {
+		return nil, newError("argument to %s must be ARRAY, got %s", name, args[0].Type())
+	}
+	return array, nil
+}
+
+func newError(format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,64 @@
+package object
+
+// Environment holds the bindings of a single scope. Lookups that miss in
+// the current scope continue in the enclosing (outer) scope, while new
+// bindings are always created in the current scope, so an inner binding
+// shadows an outer binding of the same name without modifying it.
+type Environment struct {
+	store   map[string]Object
+	outer   *Environment
+	runtime *Runtime
+}
+
+func NewEnvironment() *Environment {
+	return NewEnvironmentWithRuntime(NewRuntime())
+}
+
+// NewEnvironmentWithRuntime creates an environment for a program that runs
+// with the given settings, enclosed environments share them.
+func NewEnvironmentWithRuntime(rt *Runtime) *Environment {
+	return &Environment{
+		store:   make(map[string]Object),
+		runtime: rt,
+	}
+}
+
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironmentWithRuntime(outer.runtime)
+	env.outer = outer
+	return env
+}
+
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+func (e *Environment) Runtime() *Runtime {
+	return e.runtime
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+	val, ok := e.store[name]
+	if !ok && e.outer != nil {
+		return e.outer.Get(name)
+	}
+	return val, ok
+}
+
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+// Assign rebinds the name in the scope it is bound in, it returns false
+// when the name isn't bound in any scope.
+func (e *Environment) Assign(name string, val Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package object
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_Environment(t *testing.T) {
+	t.Run("get and set in single scope", func(t *testing.T) {
+		env := NewEnvironment()
+		env.Set("x", &Integer{Value: 2})
+
+		val, ok := env.Get("x")
+		require.True(t, ok)
+		assert.Equal(t, &Integer{Value: 2}, val)
+
+		_, ok = env.Get("y")
+		assert.False(t, ok)
+	})
+
+	t.Run("lookup continues in outer scope", func(t *testing.T) {
+		outer := NewEnvironment()
+		outer.Set("x", &Integer{Value: 1})
+
+		inner := NewEnclosedEnvironment(outer)
+		inner.Set("y", &Integer{Value: 2})
+
+		val, ok := inner.Get("x")
+		require.True(t, ok)
+		assert.Equal(t, &Integer{Value: 1}, val)
+
+		_, ok = outer.Get("y")
+		assert.False(t, ok)
+		assert.Equal(t, outer, inner.Outer())
+	})
+
+	t.Run("inner binding shadows outer binding", func(t *testing.T) {
+		outer := NewEnvironment()
+		outer.Set("x", &Integer{Value: 1})
+
+		inner := NewEnclosedEnvironment(outer)
+		inner.Set("x", &Boolean{Value: true})
+
+		val, ok := inner.Get("x")
+		require.True(t, ok)
+		assert.Equal(t, &Boolean{Value: true}, val)
+
+		val, ok = outer.Get("x")
+		require.True(t, ok)
+		assert.Equal(t, &Integer{Value: 1}, val)
+	})
+
+	t.Run("assign rebinds in the defining scope", func(t *testing.T) {
+		outer := NewEnvironment()
+		outer.Set("x", &Integer{Value: 1})
+
+		inner := NewEnclosedEnvironment(outer)
+		assert.True(t, inner.Assign("x", &Integer{Value: 2}))
+		assert.False(t, inner.Assign("y", &Integer{Value: 3}))
+
+		val, ok := outer.Get("x")
+		require.True(t, ok)
+		assert.Equal(t, &Integer{Value: 2}, val)
+
+		_, ok = inner.Get("y")
+		assert.False(t, ok)
+	})
+}
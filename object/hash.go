@@ -0,0 +1,107 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HashKey identifies a hashable value, values of different types never
+// share a key, so 1 and "1" are distinct keys.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+	Text  string
+}
+
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (b *BigInteger) HashKey() HashKey {
+	return HashKey{Type: b.Type(), Text: b.Value.String()}
+}
+
+func (b *Boolean) HashKey() HashKey {
+	if b.Value {
+		return HashKey{Type: b.Type(), Value: 1}
+	}
+	return HashKey{Type: b.Type(), Value: 0}
+}
+
+func (s *String) HashKey() HashKey {
+	return HashKey{Type: s.Type(), Text: s.Value}
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash keeps its pairs in insertion order. Like arrays, hashes are treated
+// as immutable once created, updates are made on a copy.
+type Hash struct {
+	pairs map[HashKey]int
+	order []HashPair
+}
+
+func NewHash() *Hash {
+	return &Hash{
+		pairs: make(map[HashKey]int),
+	}
+}
+
+func (h *Hash) Type() ObjectType {
+	return HASH
+}
+
+func (h *Hash) String() string {
+	pairs := make([]string, len(h.order))
+	for i, p := range h.order {
+		pairs[i] = fmt.Sprintf("%s: %s", p.Key, p.Value)
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
+}
+
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	i, ok := h.pairs[key.HashKey()]
+	if !ok {
+		return nil, false
+	}
+	return h.order[i].Value, true
+}
+
+// Set adds the pair or replaces the value of an existing key in place.
+func (h *Hash) Set(key Hashable, value Object) {
+	if i, ok := h.pairs[key.HashKey()]; ok {
+		h.order[i].Value = value
+		return
+	}
+
+	h.pairs[key.HashKey()] = len(h.order)
+	h.order = append(h.order, HashPair{Key: key, Value: value})
+}
+
+func (h *Hash) Len() int {
+	return len(h.order)
+}
+
+// Pairs returns the pairs in insertion order.
+func (h *Hash) Pairs() []HashPair {
+	pairs := make([]HashPair, len(h.order))
+	copy(pairs, h.order)
+	return pairs
+}
+
+func (h *Hash) Copy() *Hash {
+	hash := NewHash()
+	for _, p := range h.order {
+		hash.Set(p.Key.(Hashable), p.Value)
+	}
+	return hash
+}
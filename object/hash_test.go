@@ -0,0 +1,38 @@
+package object
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_HashKey(t *testing.T) {
+	assert.Equal(t, (&String{Value: "a"}).HashKey(), (&String{Value: "a"}).HashKey())
+	assert.NotEqual(t, (&String{Value: "a"}).HashKey(), (&String{Value: "b"}).HashKey())
+	assert.Equal(t, (&Integer{Value: 1}).HashKey(), (&Integer{Value: 1}).HashKey())
+	assert.NotEqual(t, (&Integer{Value: 1}).HashKey(), (&Boolean{Value: true}).HashKey())
+	assert.NotEqual(t, (&Integer{Value: 1}).HashKey(), (&String{Value: "1"}).HashKey())
+	assert.Equal(t, (&Boolean{Value: false}).HashKey(), (&Boolean{Value: false}).HashKey())
+}
+
+func Test_Hash(t *testing.T) {
+	hash := NewHash()
+	hash.Set(&String{Value: "b"}, &Integer{Value: 1})
+	hash.Set(&Integer{Value: 1}, &Boolean{Value: true})
+	hash.Set(&String{Value: "b"}, &Integer{Value: 2})
+
+	assert.Equal(t, 2, hash.Len())
+	assert.Equal(t, "{b: 2, 1: true}", hash.String())
+
+	val, ok := hash.Get(&String{Value: "b"})
+	require.True(t, ok)
+	assert.Equal(t, &Integer{Value: 2}, val)
+
+	_, ok = hash.Get(&String{Value: "c"})
+	assert.False(t, ok)
+
+	copied := hash.Copy()
+	copied.Set(&String{Value: "c"}, &Integer{Value: 3})
+	assert.Equal(t, 2, hash.Len())
+	assert.Equal(t, "{b: 2, 1: true, c: 3}", copied.String())
+}
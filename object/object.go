@@ -0,0 +1,260 @@
+package object
+
+import (
+	"fmt"
+	"language/ast"
+	"language/code"
+	"language/tokens"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+type ObjectType string
+
+const (
+	INTEGER      = "INTEGER"
+	FLOAT        = "FLOAT"
+	BOOLEAN      = "BOOLEAN"
+	STRING       = "STRING"
+	NULL         = "NULL"
+	RETURN_VALUE = "RETURN_VALUE"
+	ERROR        = "ERROR"
+	FUNCTION     = "FUNCTION"
+	BUILTIN      = "BUILTIN"
+	ARRAY        = "ARRAY"
+	HASH         = "HASH"
+
+	COMPILED_FUNCTION = "COMPILED_FUNCTION"
+	CELL              = "CELL"
+)
+
+type Object interface {
+	fmt.Stringer
+	Type() ObjectType
+}
+
+type Integer struct {
+	Value int64
+}
+
+func (i *Integer) Type() ObjectType {
+	return INTEGER
+}
+
+func (i *Integer) String() string {
+	return strconv.FormatInt(i.Value, 10)
+}
+
+// BigInteger holds integers out of the int64 range, which are only produced
+// when Runtime.BigIntegers is enabled. Results that fit in int64 are always
+// turned back into an Integer, so every integer value has a single
+// representation.
+type BigInteger struct {
+	Value *big.Int
+}
+
+func (b *BigInteger) Type() ObjectType {
+	return INTEGER
+}
+
+func (b *BigInteger) String() string {
+	return b.Value.String()
+}
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT
+}
+
+// String formats the float with the fewest digits needed to represent it,
+// a fraction is always shown so floats can be told apart from integers.
+// Very large and very small values use an exponent.
+func (f *Float) String() string {
+	format := byte('f')
+	if abs := math.Abs(f.Value); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'g'
+	}
+
+	str := strconv.FormatFloat(f.Value, format, -1, 64)
+	if strings.ContainsAny(str, ".eIN") {
+		return str
+	}
+	return str + ".0"
+}
+
+type Boolean struct {
+	Value bool
+}
+
+func (b *Boolean) Type() ObjectType {
+	return BOOLEAN
+}
+
+func (b *Boolean) String() string {
+	return strconv.FormatBool(b.Value)
+}
+
+type String struct {
+	Value string
+}
+
+func (s *String) Type() ObjectType {
+	return STRING
+}
+
+func (s *String) String() string {
+	return s.Value
+}
+
+// Array is immutable, operations on arrays produce new arrays.
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType {
+	return ARRAY
+}
+
+func (a *Array) String() string {
+	elements := make([]string, len(a.Elements))
+	for i, e := range a.Elements {
+		elements[i] = e.String()
+	}
+
+	return fmt.Sprintf("[%s]", strings.Join(elements, ", "))
+}
+
+type Null struct{}
+
+func (n *Null) Type() ObjectType {
+	return NULL
+}
+
+func (n *Null) String() string {
+	return "null"
+}
+
+// ReturnValue wraps the value of a return statement, so it can be
+// passed up through nested statements until it reaches the caller.
+type ReturnValue struct {
+	Value Object
+}
+
+func (r *ReturnValue) Type() ObjectType {
+	return RETURN_VALUE
+}
+
+func (r *ReturnValue) String() string {
+	return r.Value.String()
+}
+
+type Error struct {
+	Message string
+	Pos     tokens.Position // position of the node that caused the error
+}
+
+func (e *Error) Type() ObjectType {
+	return ERROR
+}
+
+func (e *Error) String() string {
+	return fmt.Sprintf("error: %s", e.Message)
+}
+
+// Function is a closure, it keeps the environment it was defined in, so
+// the body can resolve identifiers from the enclosing scopes when called.
+type Function struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (f *Function) Type() ObjectType {
+	return FUNCTION
+}
+
+func (f *Function) String() string {
+	params := make([]string, len(f.Parameters))
+	for i, p := range f.Parameters {
+		params[i] = p.String()
+	}
+
+	return fmt.Sprintf("fun(%s) %s", strings.Join(params, ", "), f.Body)
+}
+
+// CompiledFunction holds the bytecode of a function body, it is stored in
+// the constant pool and wrapped in a Closure when the function is created.
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	Positions     code.SourceMap
+	NumLocals     int
+	NumParameters int
+	Name          string // name the function was bound to, if any
+}
+
+func (c *CompiledFunction) Type() ObjectType {
+	return COMPILED_FUNCTION
+}
+
+func (c *CompiledFunction) String() string {
+	name := c.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	return fmt.Sprintf("fun %s/%d", name, c.NumParameters)
+}
+
+// Closure is the function value of the virtual machine, it has the same
+// type as functions of the evaluator so both report the same errors.
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []*Cell // free variables captured when the closure was created
+}
+
+func (c *Closure) Type() ObjectType {
+	return FUNCTION
+}
+
+func (c *Closure) String() string {
+	return c.Fn.String()
+}
+
+// Cell holds a variable captured by closures, so they see the same binding
+// as the function declaring it, like closures of the evaluator share its
+// environment. While that function runs the cell refers to the variable's
+// slot on the stack, when it returns the value is moved into the cell.
+type Cell struct {
+	ref   *Object
+	value Object
+}
+
+func NewCell(slot *Object) *Cell {
+	return &Cell{ref: slot}
+}
+
+func (c *Cell) Get() Object {
+	return *c.ref
+}
+
+func (c *Cell) Set(val Object) {
+	*c.ref = val
+}
+
+// Close detaches the cell from the stack slot, keeping its current value.
+func (c *Cell) Close() {
+	c.value = *c.ref
+	c.ref = &c.value
+}
+
+func (c *Cell) Type() ObjectType {
+	return CELL
+}
+
+func (c *Cell) String() string {
+	return fmt.Sprintf("cell %v", c.Get())
+}
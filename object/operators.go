@@ -0,0 +1,294 @@
+package object
+
+import (
+	"language/tokens"
+	"math"
+	"math/big"
+)
+
+// Values with a single instance, which allows booleans and null to be
+// compared by identity.
+var (
+	NullValue  = &Null{}
+	TrueValue  = &Boolean{Value: true}
+	FalseValue = &Boolean{Value: false}
+)
+
+func NativeBoolean(val bool) *Boolean {
+	if val {
+		return TrueValue
+	}
+	return FalseValue
+}
+
+func IsTruthy(obj Object) bool {
+	switch obj {
+	case NullValue, FalseValue:
+		return false
+	}
+	return true
+}
+
+// Prefix applies the prefix operator to the operand, the semantics are
+// shared by the evaluator and the virtual machine. Errors are returned
+// as *Error values.
+func (rt *Runtime) Prefix(operator string, right Object) Object {
+	switch operator {
+	case tokens.BANG:
+		return NativeBoolean(!IsTruthy(right))
+	case tokens.MINUS:
+		switch right := right.(type) {
+		case *Integer:
+			if rt.BigIntegers && right.Value == math.MinInt64 {
+				return IntegerFromBig(new(big.Int).Neg(toBig(right)))
+			}
+			return &Integer{Value: -right.Value}
+		case *BigInteger:
+			return IntegerFromBig(new(big.Int).Neg(right.Value))
+		case *Float:
+			return &Float{Value: -right.Value}
+		}
+	case tokens.BITNOT:
+		switch right := right.(type) {
+		case *Integer:
+			return &Integer{Value: ^right.Value}
+		case *BigInteger:
+			return IntegerFromBig(new(big.Int).Not(right.Value))
+		}
+	}
+
+	return newError("unknown operator: %s%s", operator, right.Type())
+}
+
+// Infix applies the infix operator to the operands.
+func (rt *Runtime) Infix(operator string, left, right Object) Object {
+	switch {
+	case left.Type() == INTEGER && right.Type() == INTEGER:
+		return integerInfix(operator, left, right, rt.BigIntegers)
+	case isNumber(left) && isNumber(right):
+		return floatInfix(operator, left, right)
+	case left.Type() == STRING && right.Type() == STRING:
+		return stringInfix(operator, left.(*String), right.(*String))
+	case left.Type() != right.Type():
+		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	case operator == tokens.EQUAL:
+		return NativeBoolean(left == right)
+	case operator == tokens.NOTEQUAL:
+		return NativeBoolean(left != right)
+	}
+
+	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
+func integerInfix(operator string, left, right Object, bigIntegers bool) Object {
+	l, lok := left.(*Integer)
+	r, rok := right.(*Integer)
+	if !lok || !rok || bigIntegers && overflows(operator, l.Value, r.Value) {
+		return bigInfix(operator, left, right)
+	}
+
+	return int64Infix(operator, l, r)
+}
+
+func int64Infix(operator string, left, right *Integer) Object {
+	switch operator {
+	case tokens.PLUS:
+		return &Integer{Value: left.Value + right.Value}
+	case tokens.MINUS:
+		return &Integer{Value: left.Value - right.Value}
+	case tokens.MULTIPLY:
+		return &Integer{Value: left.Value * right.Value}
+	case tokens.DIVIDE:
+		if right.Value == 0 {
+			return newError("division by zero: %s / %s", left, right)
+		}
+		return &Integer{Value: left.Value / right.Value}
+	case tokens.MODULO:
+		if right.Value == 0 {
+			return newError("division by zero: %s %% %s", left, right)
+		}
+		return &Integer{Value: left.Value % right.Value}
+	case tokens.POWER:
+		if right.Value < 0 {
+			return &Float{Value: math.Pow(float64(left.Value), float64(right.Value))}
+		}
+		result, _ := power(left.Value, right.Value)
+		return &Integer{Value: result}
+	case tokens.BITAND:
+		return &Integer{Value: left.Value & right.Value}
+	case tokens.BITOR:
+		return &Integer{Value: left.Value | right.Value}
+	case tokens.BITXOR:
+		return &Integer{Value: left.Value ^ right.Value}
+	case tokens.SHIFTLEFT:
+		if right.Value < 0 {
+			return newError("negative shift count: %s", right)
+		}
+		return &Integer{Value: left.Value << right.Value}
+	case tokens.SHIFTRIGHT:
+		if right.Value < 0 {
+			return newError("negative shift count: %s", right)
+		}
+		return &Integer{Value: left.Value >> right.Value}
+	case tokens.LESS:
+		return NativeBoolean(left.Value < right.Value)
+	case tokens.GREATER:
+		return NativeBoolean(left.Value > right.Value)
+	case tokens.LESSEQUAL:
+		return NativeBoolean(left.Value <= right.Value)
+	case tokens.GREATEREQUAL:
+		return NativeBoolean(left.Value >= right.Value)
+	case tokens.EQUAL:
+		return NativeBoolean(left.Value == right.Value)
+	case tokens.NOTEQUAL:
+		return NativeBoolean(left.Value != right.Value)
+	}
+
+	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
+// floatInfix applies the operator to two numbers of which at least one is
+// a float, the integer operand is promoted to a float.
+func floatInfix(operator string, left, right Object) Object {
+	l, r := toFloat(left), toFloat(right)
+
+	switch operator {
+	case tokens.PLUS:
+		return &Float{Value: l + r}
+	case tokens.MINUS:
+		return &Float{Value: l - r}
+	case tokens.MULTIPLY:
+		return &Float{Value: l * r}
+	case tokens.DIVIDE:
+		if r == 0 {
+			return newError("division by zero: %s / %s", left, right)
+		}
+		return &Float{Value: l / r}
+	case tokens.MODULO:
+		if r == 0 {
+			return newError("division by zero: %s %% %s", left, right)
+		}
+		return &Float{Value: math.Mod(l, r)}
+	case tokens.POWER:
+		return &Float{Value: math.Pow(l, r)}
+	case tokens.LESS:
+		return NativeBoolean(l < r)
+	case tokens.GREATER:
+		return NativeBoolean(l > r)
+	case tokens.LESSEQUAL:
+		return NativeBoolean(l <= r)
+	case tokens.GREATEREQUAL:
+		return NativeBoolean(l >= r)
+	case tokens.EQUAL:
+		return NativeBoolean(l == r)
+	case tokens.NOTEQUAL:
+		return NativeBoolean(l != r)
+	}
+
+	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
+func stringInfix(operator string, left, right *String) Object {
+	switch operator {
+	case tokens.PLUS:
+		return &String{Value: left.Value + right.Value}
+	case tokens.EQUAL:
+		return NativeBoolean(left.Value == right.Value)
+	case tokens.NOTEQUAL:
+		return NativeBoolean(left.Value != right.Value)
+	}
+
+	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
+// Index returns the element of an array or the value of a hash, a missing
+// hash key results in null.
+func Index(left, index Object) Object {
+	switch {
+	case left.Type() == ARRAY && index.Type() == INTEGER:
+		return arrayIndex(left.(*Array), index)
+	case left.Type() == ARRAY:
+		return newError("array index must be INTEGER, got %s", index.Type())
+	case left.Type() == HASH:
+		return hashIndex(left.(*Hash), index)
+	}
+
+	return newError("index operator not supported: %s", left.Type())
+}
+
+func arrayIndex(array *Array, index Object) Object {
+	i, ok := index.(*Integer)
+	if !ok || i.Value < 0 || i.Value >= int64(len(array.Elements)) {
+		return newError("index out of range: %s with length %d", index, len(array.Elements))
+	}
+
+	return array.Elements[i.Value]
+}
+
+func hashIndex(hash *Hash, index Object) Object {
+	key, ok := index.(Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+
+	if val, ok := hash.Get(key); ok {
+		return val
+	}
+	return NullValue
+}
+
+// Assign returns the new value of a variable holding current, and the
+// value assigned to the element at the path of indices, or to the variable
+// itself without indices. A compound assignment combines the previous value
+// with the value by the infix operator. Collections are copied rather than
+// modified, so other references to them are unaffected. Errors are returned
+// as the new value.
+func (rt *Runtime) Assign(current Object, indices []Object, operator string, value Object) (Object, Object) {
+	if len(indices) == 0 {
+		if operator != "" {
+			value = rt.Infix(operator, current, value)
+		}
+		return value, value
+	}
+
+	element := Index(current, indices[0])
+	if _, ok := element.(*Error); ok {
+		return element, nil
+	}
+
+	element, assigned := rt.Assign(element, indices[1:], operator, value)
+	if _, ok := element.(*Error); ok {
+		return element, nil
+	}
+
+	switch current := current.(type) {
+	case *Array:
+		elements := make([]Object, len(current.Elements))
+		copy(elements, current.Elements)
+		elements[indices[0].(*Integer).Value] = element
+		return &Array{Elements: elements}, assigned
+	case *Hash:
+		hash := current.Copy()
+		hash.Set(indices[0].(Hashable), element)
+		return hash, assigned
+	}
+
+	return newError("index assignment not supported: %s", current.Type()), nil
+}
+
+func isNumber(obj Object) bool {
+	return obj.Type() == INTEGER || obj.Type() == FLOAT
+}
+
+func toFloat(obj Object) float64 {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value)
+	case *BigInteger:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
+	case *Float:
+		return obj.Value
+	}
+	return 0
+}
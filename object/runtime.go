@@ -0,0 +1,43 @@
+package object
+
+import (
+	"io"
+	"os"
+)
+
+// MaxCallDepth limits the number of nested function calls, deeper calls
+// fail with a stack overflow in both the evaluator and the virtual machine.
+const MaxCallDepth = 4096
+
+// Runtime holds the settings of a running program, it is shared by the
+// evaluator and the virtual machine and passed to the builtins.
+type Runtime struct {
+	Stdout io.Writer // where builtins write their output
+
+	// BigIntegers enables arbitrary-precision integers, integer results
+	// that don't fit in int64 are promoted to BigInteger instead of
+	// wrapping around.
+	BigIntegers bool
+
+	depth int // number of function calls in progress
+}
+
+// NewRuntime returns the default settings, output is written to os.Stdout.
+func NewRuntime() *Runtime {
+	return &Runtime{Stdout: os.Stdout}
+}
+
+// EnterCall records the start of a function call, it returns false when
+// the call would be nested deeper than MaxCallDepth.
+func (rt *Runtime) EnterCall() bool {
+	if rt.depth >= MaxCallDepth {
+		return false
+	}
+	rt.depth++
+	return true
+}
+
+// LeaveCall records the end of a call started by EnterCall.
+func (rt *Runtime) LeaveCall() {
+	rt.depth--
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"fmt"
+	"language/tokens"
+	"strings"
+)
+
+type ParseError struct {
+	Token    tokens.Token       // offending token
+	Expected []tokens.TokenType // token types expected instead of the offending token, if any
+	Context  string             // construct being parsed when the error occurred, e.g. "let statement"
+	Message  string
+	Position tokens.Position // position inside the token the error was found at, if it isn't the token start
+}
+
+func (e *ParseError) Pos() tokens.Position {
+	if e.Position.IsValid() {
+		return e.Position
+	}
+	return e.Token.Pos
+}
+
+func (e *ParseError) Error() string {
+	if e.Context != "" {
+		return fmt.Sprintf("parsing %s failed: %s", e.Context, e.Message)
+	}
+	return e.Message
+}
+
+// ParseErrors is a list of all the errors found while parsing the program,
+// ordered as they were encountered in the source.
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = fmt.Sprintf("%s: %s", err.Pos(), err)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e ParseErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+func describeType(t tokens.TokenType) string {
+	if t == tokens.EOF {
+		return "EOF"
+	}
+	return string(t)
+}
@@ -1,22 +1,34 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"language/ast"
 	"language/lexer"
 	"language/tokens"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // = += -= *= /=
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
 	EQUALS      // ==
-	LESSGREATER // < >
+	LESSGREATER // < > <= >=
+	BITWISE_OR  // |
+	BITWISE_XOR // ^
+	BITWISE_AND // &
+	SHIFT       // << >>
 	SUM         // +
-	PRODUCT     // *
-	PREFIX      // -X !X
+	PRODUCT     // * / %
+	PREFIX      // -X !X ~X
+	POWER       // **
 	CALL        // foo()
+	INDEX       // array[index]
 )
 
 type (
@@ -30,15 +42,30 @@ type Parser struct {
 	token     tokens.Token
 	peekToken tokens.Token
 
-	errors []error
+	errors ParseErrors
+	// panicking is set after an error until the parser synchronizes on the
+	// next statement, errors found in the meantime are not reported
+	panicking bool
 
 	prefixParsers map[tokens.TokenType]prefixParse
 	infixParsers  map[tokens.TokenType]infixParse
 
 	tokenPrecedences map[tokens.TokenType]int
+
+	bigIntegers bool
 }
 
-func New(l *lexer.Lexer) *Parser {
+type Option func(*Parser)
+
+// WithBigIntegers accepts integer literals out of the int64 range, which
+// are parsed into IntegerLiteral.Big.
+func WithBigIntegers() Option {
+	return func(p *Parser) {
+		p.bigIntegers = true
+	}
+}
+
+func New(l *lexer.Lexer, opts ...Option) *Parser {
 	parser := &Parser{
 		lexer: l,
 
@@ -46,23 +73,50 @@ func New(l *lexer.Lexer) *Parser {
 		infixParsers:  make(map[tokens.TokenType]infixParse),
 
 		tokenPrecedences: map[tokens.TokenType]int{
-			tokens.MINUS:    SUM,
-			tokens.PLUS:     SUM,
-			tokens.LESS:     LESSGREATER,
-			tokens.GREATER:  LESSGREATER,
-			tokens.EQUAL:    EQUALS,
-			tokens.MULTIPLY: PRODUCT,
-			tokens.DIVIDE:   PRODUCT,
+			tokens.ASSIGN:         ASSIGN,
+			tokens.PLUSASSIGN:     ASSIGN,
+			tokens.MINUSASSIGN:    ASSIGN,
+			tokens.MULTIPLYASSIGN: ASSIGN,
+			tokens.DIVIDEASSIGN:   ASSIGN,
+			tokens.MINUS:          SUM,
+			tokens.PLUS:           SUM,
+			tokens.LESS:           LESSGREATER,
+			tokens.GREATER:        LESSGREATER,
+			tokens.LESSEQUAL:      LESSGREATER,
+			tokens.GREATEREQUAL:   LESSGREATER,
+			tokens.BITOR:          BITWISE_OR,
+			tokens.BITXOR:         BITWISE_XOR,
+			tokens.BITAND:         BITWISE_AND,
+			tokens.SHIFTLEFT:      SHIFT,
+			tokens.SHIFTRIGHT:     SHIFT,
+			tokens.OR:             LOGICAL_OR,
+			tokens.AND:            LOGICAL_AND,
+			tokens.EQUAL:          EQUALS,
+			tokens.NOTEQUAL:       EQUALS,
+			tokens.MULTIPLY:       PRODUCT,
+			tokens.DIVIDE:         PRODUCT,
+			tokens.MODULO:         PRODUCT,
+			tokens.POWER:          POWER,
+			tokens.LPAREN:         CALL,
+			tokens.LBRACKET:       INDEX,
 		},
 	}
 
 	parser.registerPrefix(tokens.IDENTIFIER, parser.parseIdentifier)
 	parser.registerPrefix(tokens.INT, parser.parseIntegerLiteral)
+	parser.registerPrefix(tokens.FLOAT, parser.parseFloatLiteral)
+	parser.registerPrefix(tokens.STRING, parser.parseStringLiteral)
 	parser.registerPrefix(tokens.TRUE, parser.parseBooleanLiteral)
 	parser.registerPrefix(tokens.FALSE, parser.parseBooleanLiteral)
 	parser.registerPrefix(tokens.BANG, parser.parsePrefixExpression)
 	parser.registerPrefix(tokens.MINUS, parser.parsePrefixExpression)
+	parser.registerPrefix(tokens.BITNOT, parser.parsePrefixExpression)
 	parser.registerPrefix(tokens.LPAREN, parser.parseGroupExpression)
+	parser.registerPrefix(tokens.FUN, parser.parseFunctionLiteral)
+	parser.registerPrefix(tokens.IF, parser.parseIfExpression)
+	parser.registerPrefix(tokens.LBRACKET, parser.parseArrayLiteral)
+	parser.registerPrefix(tokens.LBRACE, parser.parseHashLiteral)
+	parser.registerPrefix(tokens.INVALID, parser.parseInvalid)
 
 	parser.registerInfix(tokens.PLUS, parser.parseInfixExpression)
 	parser.registerInfix(tokens.MINUS, parser.parseInfixExpression)
@@ -70,8 +124,30 @@ func New(l *lexer.Lexer) *Parser {
 	parser.registerInfix(tokens.MULTIPLY, parser.parseInfixExpression)
 	parser.registerInfix(tokens.EQUAL, parser.parseInfixExpression)
 	parser.registerInfix(tokens.NOTEQUAL, parser.parseInfixExpression)
+	parser.registerInfix(tokens.AND, parser.parseInfixExpression)
+	parser.registerInfix(tokens.OR, parser.parseInfixExpression)
 	parser.registerInfix(tokens.LESS, parser.parseInfixExpression)
 	parser.registerInfix(tokens.GREATER, parser.parseInfixExpression)
+	parser.registerInfix(tokens.LESSEQUAL, parser.parseInfixExpression)
+	parser.registerInfix(tokens.GREATEREQUAL, parser.parseInfixExpression)
+	parser.registerInfix(tokens.MODULO, parser.parseInfixExpression)
+	parser.registerInfix(tokens.POWER, parser.parseInfixExpression)
+	parser.registerInfix(tokens.BITAND, parser.parseInfixExpression)
+	parser.registerInfix(tokens.BITOR, parser.parseInfixExpression)
+	parser.registerInfix(tokens.BITXOR, parser.parseInfixExpression)
+	parser.registerInfix(tokens.SHIFTLEFT, parser.parseInfixExpression)
+	parser.registerInfix(tokens.SHIFTRIGHT, parser.parseInfixExpression)
+	parser.registerInfix(tokens.ASSIGN, parser.parseAssignExpression)
+	parser.registerInfix(tokens.PLUSASSIGN, parser.parseAssignExpression)
+	parser.registerInfix(tokens.MINUSASSIGN, parser.parseAssignExpression)
+	parser.registerInfix(tokens.MULTIPLYASSIGN, parser.parseAssignExpression)
+	parser.registerInfix(tokens.DIVIDEASSIGN, parser.parseAssignExpression)
+	parser.registerInfix(tokens.LPAREN, parser.parseCallExpression)
+	parser.registerInfix(tokens.LBRACKET, parser.parseIndexExpression)
+
+	for _, opt := range opts {
+		opt(parser)
+	}
 
 	// we fill current token and peek token, so they are not empty
 	parser.nextToken()
@@ -81,18 +157,79 @@ func New(l *lexer.Lexer) *Parser {
 }
 
 func (p *Parser) Parse() (*ast.Program, error) {
-	program := &ast.Program{}
+	program := &ast.Program{
+		Statements: p.parseStatements(tokens.EOF),
+	}
+
+	if len(p.errors) > 0 {
+		return program, p.errors
+	}
+	return program, nil
+}
+
+// Errors returns all the errors found by Parse.
+func (p *Parser) Errors() ParseErrors {
+	return p.errors
+}
 
-	for !p.isPeekType(tokens.EOF) {
+// parseStatements parses statements until the end token is reached, after
+// a failed statement the parser synchronizes and continues with the next one.
+func (p *Parser) parseStatements(end tokens.TokenType) []ast.Statement {
+	var statements []ast.Statement
+
+	for !p.isType(end) && !p.isType(tokens.EOF) {
+		start := p.token
 		st := p.parseStatement()
+
+		if p.panicking {
+			p.synchronize(start, end)
+			continue
+		}
+
 		if st != nil {
-			program.Statements = append(program.Statements, st)
+			statements = append(statements, st)
 		}
 
 		p.nextToken()
 	}
 
-	return program, nil
+	return statements
+}
+
+// synchronize skips tokens until the start of the next statement, which is
+// the token after a semicolon, a statement keyword or the closing brace of
+// the enclosing block. Nested blocks are skipped as a whole.
+func (p *Parser) synchronize(start tokens.Token, end tokens.TokenType) {
+	defer func() {
+		p.panicking = false
+	}()
+
+	depth := 0
+	for !p.isType(tokens.EOF) {
+		switch p.token.Type {
+		case tokens.LBRACE:
+			depth++
+		case tokens.RBRACE:
+			if depth == 0 {
+				if end != tokens.RBRACE {
+					p.nextToken() // unbalanced brace outside any block
+				}
+				return
+			}
+			depth--
+		case tokens.SEMICOLON:
+			if depth == 0 {
+				p.nextToken()
+				return
+			}
+		case tokens.LET, tokens.RETURN:
+			if depth == 0 && p.token != start {
+				return
+			}
+		}
+
+		p.nextToken()
+	}
 }
 
 func (p *Parser) parseStatement() ast.Statement {
@@ -110,9 +247,18 @@ func (p *Parser) parseStatement() ast.Statement {
 	return st
 }
 
+// nextToken advances to the next token, comments are skipped when the
+// lexer produces them.
 func (p *Parser) nextToken() {
 	p.token = p.peekToken
 	p.peekToken = p.lexer.NextToken()
+	for p.peekToken.Type == tokens.COMMENT {
+		p.peekToken = p.lexer.NextToken()
+	}
+}
+
+func (p *Parser) isType(t tokens.TokenType) bool {
+	return p.token.Type == t
 }
 
 func (p *Parser) isPeekType(t tokens.TokenType) bool {
@@ -129,7 +275,16 @@ func (p *Parser) lookupPrecedence(token tokens.Token) int {
 
 func (p *Parser) expectPeekType(t tokens.TokenType) bool {
 	if !p.isPeekType(t) {
-		p.addParseError(fmt.Errorf("expected %s, got %s", t, p.peekToken.Type))
+		err := &ParseError{
+			Token:   p.peekToken,
+			Message: fmt.Sprintf("expected %s, got %s", describeType(t), describeType(p.peekToken.Type)),
+		}
+		if p.isPeekType(tokens.INVALID) {
+			err = p.invalidError(p.peekToken)
+		}
+
+		err.Expected = []tokens.TokenType{t}
+		p.addParseError(err)
 		return false
 	}
 
@@ -137,6 +292,14 @@ func (p *Parser) expectPeekType(t tokens.TokenType) bool {
 	return true
 }
 
+// skipSemicolon consumes the optional semicolon ending a statement, unless
+// the statement failed, in which case it is left for synchronization.
+func (p *Parser) skipSemicolon() {
+	if p.isPeekType(tokens.SEMICOLON) && !p.panicking {
+		p.nextToken()
+	}
+}
+
 func (p *Parser) registerPrefix(token tokens.TokenType, parser prefixParse) {
 	p.prefixParsers[token] = parser
 }
@@ -145,8 +308,22 @@ func (p *Parser) registerInfix(token tokens.TokenType, parser infixParse) {
 	p.infixParsers[token] = parser
 }
 
-func (p *Parser) addParseError(err error) {
+func (p *Parser) addParseError(err *ParseError) {
+	if p.panicking {
+		return
+	}
+
 	p.errors = append(p.errors, err)
+	p.panicking = true
+}
+
+// failStatement records the statement being parsed as the context of the
+// last error and returns the nil statement.
+func (p *Parser) failStatement(context string) ast.Statement {
+	if len(p.errors) > 0 && p.errors[len(p.errors)-1].Context == "" {
+		p.errors[len(p.errors)-1].Context = context
+	}
+	return nil
 }
 
 /*
@@ -155,13 +332,11 @@ func (p *Parser) addParseError(err error) {
 
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	st := &ast.ExpressionStatement{
-		Token:      p.token,
-		Expression: p.parseExpression(LOWEST),
+		Token: p.token,
 	}
+	st.Expression = p.parseExpression(LOWEST)
 
-	if p.isPeekType(tokens.SEMICOLON) {
-		p.nextToken()
-	}
+	p.skipSemicolon()
 
 	return st
 }
@@ -169,12 +344,15 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	parser, exists := p.prefixParsers[p.token.Type]
 	if !exists {
-		p.addParseError(fmt.Errorf("no prefix parser found for token %s", p.token))
+		p.addParseError(&ParseError{
+			Token:   p.token,
+			Message: fmt.Sprintf("no prefix parser found for %s", describeType(p.token.Type)),
+		})
 		return nil
 	}
 	leftExpr := parser()
 
-	for precedence < p.peekPrecedence() && !p.isPeekType(tokens.SEMICOLON) {
+	for precedence < p.peekPrecedence() && !p.isPeekType(tokens.SEMICOLON) && !p.panicking {
 		infixParser, exists := p.infixParsers[p.peekToken.Type]
 		if !exists {
 			return leftExpr
@@ -219,6 +397,11 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.lookupPrecedence(p.token)
+	// exponentiation is right-associative, so the right operand takes
+	// in the following operators of the same precedence
+	if p.isType(tokens.POWER) {
+		precedence--
+	}
 
 	p.nextToken()
 	infix.Right = p.parseExpression(precedence)
@@ -226,6 +409,226 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return infix
 }
 
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	assign := &ast.AssignExpression{
+		Token:    p.token,
+		Target:   target,
+		Operator: p.token.Literal,
+	}
+
+	if variable, _ := assign.Variable(); variable == nil {
+		p.addParseError(&ParseError{
+			Token:   p.token,
+			Message: fmt.Sprintf("cannot assign to %s", target),
+		})
+		return nil
+	}
+
+	// assignment is right-associative, so a = b = 1 assigns 1 to both
+	p.nextToken()
+	assign.Value = p.parseExpression(ASSIGN - 1)
+
+	return assign
+}
+
+func (p *Parser) parseIfExpression() ast.Expression {
+	exp := &ast.IfExpression{
+		Token: p.token, // if
+	}
+
+	p.nextToken()
+	exp.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeekType(tokens.LBRACE) {
+		return nil
+	}
+	exp.Consequence = p.parseBlockStatement()
+
+	if !p.isPeekType(tokens.ELSE) {
+		return exp
+	}
+	p.nextToken() // else
+
+	// else if chains are parsed as an alternative block holding the nested if
+	if p.isPeekType(tokens.IF) {
+		p.nextToken()
+		alternative := &ast.BlockStatement{Token: p.token}
+		nested := p.parseIfExpression()
+		if nested == nil {
+			return nil
+		}
+		alternative.Statements = []ast.Statement{
+			&ast.ExpressionStatement{Token: alternative.Token, Expression: nested},
+		}
+		alternative.EndToken = p.token
+		exp.Alternative = alternative
+		return exp
+	}
+
+	if !p.expectPeekType(tokens.LBRACE) {
+		return nil
+	}
+	exp.Alternative = p.parseBlockStatement()
+
+	return exp
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	call := &ast.CallExpression{
+		Token:    p.token, // (
+		Function: function,
+	}
+
+	args, ok := p.parseExpressionList(tokens.RPAREN)
+	if !ok {
+		return nil
+	}
+
+	call.Arguments = args
+	call.EndToken = p.token
+	return call
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{
+		Token: p.token, // [
+	}
+
+	elements, ok := p.parseExpressionList(tokens.RBRACKET)
+	if !ok {
+		return nil
+	}
+
+	array.Elements = elements
+	array.EndToken = p.token
+	return array
+}
+
+// parseHashLiteral parses a brace in expression position, blocks are only
+// expected after if, else and fun, where parseBlockStatement is used instead.
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{
+		Token: p.token, // {
+	}
+
+	for !p.isPeekType(tokens.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeekType(tokens.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if !p.isPeekType(tokens.RBRACE) && !p.expectPeekType(tokens.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeekType(tokens.RBRACE) {
+		return nil
+	}
+
+	hash.EndToken = p.token
+	return hash
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{
+		Token: p.token, // [
+		Left:  left,
+	}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeekType(tokens.RBRACKET) {
+		return nil
+	}
+
+	exp.EndToken = p.token
+	return exp
+}
+
+func (p *Parser) parseExpressionList(end tokens.TokenType) ([]ast.Expression, bool) {
+	list := make([]ast.Expression, 0)
+
+	if p.isPeekType(end) {
+		p.nextToken()
+		return list, true
+	}
+
+	p.nextToken()
+	list = append(list, p.parseExpression(LOWEST))
+
+	for p.isPeekType(tokens.COMMA) {
+		p.nextToken() // ,
+		p.nextToken()
+		list = append(list, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeekType(end) {
+		return nil, false
+	}
+
+	return list, true
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	fun := &ast.FunctionLiteral{
+		Token: p.token, // fun
+	}
+
+	if !p.expectPeekType(tokens.LPAREN) {
+		return nil
+	}
+
+	params, ok := p.parseFunctionParameters()
+	if !ok {
+		return nil
+	}
+	fun.Parameters = params
+
+	if !p.expectPeekType(tokens.LBRACE) {
+		return nil
+	}
+
+	fun.Body = p.parseBlockStatement()
+	return fun
+}
+
+func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, bool) {
+	params := make([]*ast.Identifier, 0)
+
+	if p.isPeekType(tokens.RPAREN) {
+		p.nextToken()
+		return params, true
+	}
+
+	if !p.expectPeekType(tokens.IDENTIFIER) {
+		return nil, false
+	}
+	params = append(params, &ast.Identifier{Token: p.token, Value: p.token.Literal})
+
+	for p.isPeekType(tokens.COMMA) {
+		p.nextToken() // ,
+		if !p.expectPeekType(tokens.IDENTIFIER) {
+			return nil, false
+		}
+		params = append(params, &ast.Identifier{Token: p.token, Value: p.token.Literal})
+	}
+
+	if !p.expectPeekType(tokens.RPAREN) {
+		return nil, false
+	}
+
+	return params, true
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{
 		Token: p.token,
@@ -234,17 +637,99 @@ func (p *Parser) parseIdentifier() ast.Expression {
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
-	integer := &ast.IntegerLiteral{
+	val, err := parseInteger(p.token.Literal)
+	if errors.Is(err, strconv.ErrRange) && p.bigIntegers {
+		return &ast.IntegerLiteral{
+			Token: p.token,
+			Big:   parseBigInteger(p.token.Literal),
+		}
+	}
+	if err != nil {
+		p.addNumberError("integer", err)
+		return nil
+	}
+
+	return &ast.IntegerLiteral{
 		Token: p.token,
+		Value: val,
+	}
+}
+
+// parseInteger parses decimal literals and hex, octal and binary literals
+// prefixed with 0x, 0o and 0b, digits can be separated by underscores.
+func parseInteger(literal string) (int64, error) {
+	if len(literal) > 1 && literal[0] == '0' && strings.ContainsRune("xXoObB", rune(literal[1])) {
+		return strconv.ParseInt(literal, 0, 64)
+	}
+
+	// base 0 would read a leading zero as an octal prefix, decimal literals
+	// with a leading zero are rejected instead of silently read as decimal
+	if strings.HasSuffix(literal, "_") || strings.Contains(literal, "__") || len(literal) > 1 && literal[0] == '0' {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.ParseInt(strings.ReplaceAll(literal, "_", ""), 10, 64)
+}
+
+// parseBigInteger parses a literal already validated by parseInteger.
+func parseBigInteger(literal string) *big.Int {
+	base := 0
+	if !strings.ContainsAny(literal, "xXoObB") {
+		base = 10
+		literal = strings.ReplaceAll(literal, "_", "")
 	}
 
-	val, err := strconv.ParseInt(p.token.Literal, 10, 64)
+	val, _ := new(big.Int).SetString(literal, base)
+	return val
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	val, err := strconv.ParseFloat(p.token.Literal, 64)
 	if err != nil {
+		p.addNumberError("float", err)
 		return nil
 	}
 
-	integer.Value = val
-	return integer
+	return &ast.FloatLiteral{
+		Token: p.token,
+		Value: val,
+	}
+}
+
+func (p *Parser) addNumberError(kind string, err error) {
+	message := fmt.Sprintf("invalid %s literal %s", kind, p.token.Literal)
+	if errors.Is(err, strconv.ErrRange) {
+		message = fmt.Sprintf("%s literal %s out of range", kind, p.token.Literal)
+	}
+
+	p.addParseError(&ParseError{
+		Token:   p.token,
+		Message: message,
+	})
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{
+		Token: p.token,
+		Value: p.token.Literal,
+	}
+}
+
+// parseInvalid reports the lexer error for the malformed input covered by
+// the invalid token.
+func (p *Parser) parseInvalid() ast.Expression {
+	p.addParseError(p.invalidError(p.token))
+	return nil
+}
+
+// invalidError returns the error for the invalid token, which has the
+// message and position of the lexer error found in the input it covers.
+func (p *Parser) invalidError(token tokens.Token) *ParseError {
+	for _, err := range p.lexer.Errors() {
+		if err.Pos.Offset >= token.Pos.Offset && err.Pos.Offset < token.End.Offset {
+			return &ParseError{Token: token, Message: err.Message, Position: err.Pos}
+		}
+	}
+	return &ParseError{Token: token, Message: fmt.Sprintf("unexpected %q", token.Literal)}
 }
 
 func (p *Parser) parseBooleanLiteral() ast.Expression {
@@ -264,7 +749,7 @@ func (p *Parser) parseLetStatement() ast.Statement {
 	}
 
 	if !p.expectPeekType(tokens.IDENTIFIER) {
-		return nil
+		return p.failStatement("let statement")
 	}
 
 	st.Identifier = ast.Identifier{
@@ -273,28 +758,53 @@ func (p *Parser) parseLetStatement() ast.Statement {
 	}
 
 	if !p.expectPeekType(tokens.ASSIGN) {
-		return nil
+		return p.failStatement("let statement")
 	}
 
 	p.nextToken() // assign =
 
 	st.Value = p.parseExpression(LOWEST)
 
-	for p.token.Type != tokens.SEMICOLON {
-		p.nextToken()
-	}
+	p.skipSemicolon()
 
 	return st
 }
 
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{
+		Token: p.token, // {
+	}
+
+	p.nextToken()
+	block.Statements = p.parseStatements(tokens.RBRACE)
+
+	if !p.isType(tokens.RBRACE) {
+		p.addParseError(&ParseError{
+			Token:    p.token,
+			Expected: []tokens.TokenType{tokens.RBRACE},
+			Message:  fmt.Sprintf("expected }, got %s", describeType(p.token.Type)),
+		})
+	}
+
+	block.EndToken = p.token
+	return block
+}
+
 func (p *Parser) parseReturnStatement() ast.Statement {
 	st := &ast.ReturnStatement{
 		Token: p.token,
 	}
 
-	for p.token.Type != tokens.SEMICOLON {
-		p.nextToken()
+	// bare return, the value is left out
+	if p.isPeekType(tokens.SEMICOLON) || p.isPeekType(tokens.RBRACE) || p.isPeekType(tokens.EOF) {
+		p.skipSemicolon()
+		return st
 	}
 
+	p.nextToken()
+	st.Value = p.parseExpression(LOWEST)
+
+	p.skipSemicolon()
+
 	return st
 }
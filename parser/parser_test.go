@@ -26,6 +26,18 @@ func parseStatementsWithLen(t *testing.T, input string, statementsLen int) (*Par
 	return p, program.Statements
 }
 
+func parseWithErrors(t *testing.T, input string) (*ast.Program, ParseErrors) {
+	p := New(lexer.New(input))
+	program, err := p.Parse()
+	require.Error(t, err, fmt.Sprintf("parsing input %s didn't fail", input))
+
+	var errs ParseErrors
+	require.ErrorAs(t, err, &errs)
+	require.Equal(t, p.Errors(), errs)
+
+	return program, errs
+}
+
 func assertIntegerLiteral(t *testing.T, expression ast.Expression, value int64) {
 	lit, ok := expression.(*ast.IntegerLiteral)
 	require.True(t, ok)
@@ -40,7 +52,7 @@ func Test_LetStatement(t *testing.T) {
 			let x = 2;
 		`
 		p, statements := parseStatementsWithLen(t, input, 3)
-		require.Len(t, p.errors, 0)
+		require.Len(t, p.Errors(), 0)
 
 		identifiers := []string{"foo", "boo", "x"}
 
@@ -61,8 +73,9 @@ func Test_LetStatement(t *testing.T) {
 			let 200;
 			let x;
 		`
-		p, _ := parseStatementsWithLen(t, input, 0)
-		require.Len(t, p.errors, 4)
+		program, errs := parseWithErrors(t, input)
+		require.Len(t, program.Statements, 0)
+		require.Len(t, errs, 4)
 
 		errors := []string{
 			"parsing let statement failed: expected IDENTIFIER, got =",
@@ -71,7 +84,7 @@ func Test_LetStatement(t *testing.T) {
 			"parsing let statement failed: expected =, got ;",
 		}
 
-		for i, err := range p.errors {
+		for i, err := range errs {
 			assert.Equal(t, errors[i], err.Error(), fmt.Sprintf("test case %d failed", i))
 		}
 	})
@@ -86,7 +99,37 @@ func Test_ReturnStatement(t *testing.T) {
 		`
 
 		p, _ := parseStatementsWithLen(t, input, 2)
-		require.Len(t, p.errors, 0)
+		require.Len(t, p.Errors(), 0)
+	})
+
+	t.Run("parse return values", func(t *testing.T) {
+		tests := []struct {
+			in  string
+			out string
+		}{
+			{in: "return 1;", out: "return 1;"},
+			{in: "return x;", out: "return x;"},
+			{in: "return 1 + 2;", out: "return (1 + 2);"},
+			{in: "return add(1, 2) * 3", out: "return (add(1, 2) * 3);"},
+			{in: "return fun(x) { return x; };", out: "return fun(x) { return x; };"},
+			{in: "return;", out: "return;"},
+		}
+
+		for _, test := range tests {
+			p, statements := parseStatementsWithLen(t, test.in, 1)
+			require.Len(t, p.Errors(), 0)
+
+			st, ok := statements[0].(*ast.ReturnStatement)
+			require.True(t, ok)
+			assert.Equal(t, "return", st.TokenLiteral())
+			assert.Equal(t, test.out, st.String())
+		}
+	})
+
+	t.Run("parse bare return inside block", func(t *testing.T) {
+		p, statements := parseStatementsWithLen(t, "fun() { return }; 1;", 2)
+		require.Len(t, p.Errors(), 0)
+		assert.Equal(t, "fun() { return; }", statements[0].String())
 	})
 }
 
@@ -107,7 +150,7 @@ func Test_ProgramStringer(t *testing.T) {
 func Test_Identifier(t *testing.T) {
 	input := `foo;`
 	p, statements := parseStatementsWithLen(t, input, 1)
-	require.Len(t, p.errors, 0)
+	require.Len(t, p.Errors(), 0)
 
 	stm, ok := statements[0].(*ast.ExpressionStatement)
 	assert.True(t, ok)
@@ -122,7 +165,7 @@ func Test_Identifier(t *testing.T) {
 func Test_Integer(t *testing.T) {
 	input := `1337;`
 	p, statements := parseStatementsWithLen(t, input, 1)
-	require.Len(t, p.errors, 0)
+	require.Len(t, p.Errors(), 0)
 
 	stm, ok := statements[0].(*ast.ExpressionStatement)
 	require.True(t, ok)
@@ -130,10 +173,101 @@ func Test_Integer(t *testing.T) {
 	exp, ok := stm.Expression.(*ast.IntegerLiteral)
 	require.True(t, ok)
 
-	assert.Equal(t, 1337, exp.Value)
+	assert.Equal(t, int64(1337), exp.Value)
 	assert.Equal(t, "1337", exp.TokenLiteral())
 }
 
+func Test_IntegerBases(t *testing.T) {
+	tests := []struct {
+		in  string
+		out int64
+	}{
+		{in: "0xff", out: 255},
+		{in: "0XFF", out: 255},
+		{in: "0o755", out: 493},
+		{in: "0b1010", out: 10},
+		{in: "1_000_000", out: 1000000},
+		{in: "0x_ff_ff", out: 65535},
+		{in: "0", out: 0},
+		{in: "9223372036854775807", out: 9223372036854775807},
+	}
+
+	for _, test := range tests {
+		_, statements := parseStatementsWithLen(t, test.in, 1)
+
+		exp, ok := statements[0].(*ast.ExpressionStatement).Expression.(*ast.IntegerLiteral)
+		require.True(t, ok, test.in)
+		assert.Equal(t, test.out, exp.Value, test.in)
+		assert.Equal(t, test.in, exp.String(), test.in)
+	}
+}
+
+func Test_IntegerErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "let a = 9223372036854775808;", out: "1:9: integer literal 9223372036854775808 out of range"},
+		{in: "let a =\n  0xffffffffffffffff;", out: "2:3: integer literal 0xffffffffffffffff out of range"},
+		{in: "let a = 0b102;", out: "1:9: invalid integer literal 0b102"},
+		{in: "let a = 0x;", out: "1:9: invalid integer literal 0x"},
+		{in: "let a = 1__000;", out: "1:9: invalid integer literal 1__000"},
+		{in: "let a = 1_;", out: "1:9: invalid integer literal 1_"},
+		{in: "let a = 0755;", out: "1:9: invalid integer literal 0755"},
+		{in: "let a = 00;", out: "1:9: invalid integer literal 00"},
+		{in: "let a = 0_1;", out: "1:9: invalid integer literal 0_1"},
+		{in: "let a = 1_.5;", out: "1:9: invalid float literal 1_.5"},
+	}
+
+	for _, test := range tests {
+		_, errs := parseWithErrors(t, test.in)
+		require.Len(t, errs, 1, test.in)
+		assert.Equal(t, test.out, fmt.Sprintf("%s: %s", errs[0].Pos(), errs[0]))
+	}
+}
+
+func Test_BigIntegerLiteral(t *testing.T) {
+	for _, input := range []string{"9223372036854775808", "0x8000_0000_0000_0000", "9_223_372_036_854_775_808"} {
+		p := New(lexer.New(input), WithBigIntegers())
+		program, err := p.Parse()
+		require.NoError(t, err)
+
+		lit, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IntegerLiteral)
+		require.True(t, ok)
+		require.NotNil(t, lit.Big, input)
+		assert.Equal(t, "9223372036854775808", lit.Big.String())
+		assert.Equal(t, input, lit.String())
+	}
+
+	p := New(lexer.New("9223372036854775807"), WithBigIntegers())
+	program, err := p.Parse()
+	require.NoError(t, err)
+	assert.Nil(t, program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IntegerLiteral).Big)
+}
+
+func Test_Float(t *testing.T) {
+	tests := []struct {
+		in  string
+		out float64
+	}{
+		{in: "3.14;", out: 3.14},
+		{in: ".5;", out: 0.5},
+		{in: "1e-9;", out: 1e-9},
+	}
+
+	for _, test := range tests {
+		_, statements := parseStatementsWithLen(t, test.in, 1)
+
+		exp, ok := statements[0].(*ast.ExpressionStatement).Expression.(*ast.FloatLiteral)
+		require.True(t, ok, test.in)
+		assert.Equal(t, test.out, exp.Value)
+	}
+
+	_, errs := parseWithErrors(t, "let a = 1e400;")
+	require.Len(t, errs, 1)
+	assert.Equal(t, "1:9: float literal 1e400 out of range", fmt.Sprintf("%s: %s", errs[0].Pos(), errs[0]))
+}
+
 func Test_PrefixExpressions(t *testing.T) {
 	input := "!foo; -5;"
 
@@ -147,7 +281,7 @@ func Test_PrefixExpressions(t *testing.T) {
 	}
 
 	p, statements := parseStatementsWithLen(t, input, 2)
-	require.Len(t, p.errors, 0)
+	require.Len(t, p.Errors(), 0)
 
 	for i, stm := range statements {
 		st, ok := stm.(*ast.ExpressionStatement)
@@ -177,11 +311,20 @@ func Test_InfixExpressionSimple(t *testing.T) {
 		{in: "3 < 2", left: 3, operator: "<", right: 2},
 		{in: "10 > 2", left: 10, operator: ">", right: 2},
 		{in: "2 != 2", left: 2, operator: "!=", right: 2},
+		{in: "7 % 2", left: 7, operator: "%", right: 2},
+		{in: "2 ** 8", left: 2, operator: "**", right: 8},
+		{in: "1 <= 2", left: 1, operator: "<=", right: 2},
+		{in: "3 >= 2", left: 3, operator: ">=", right: 2},
+		{in: "6 & 3", left: 6, operator: "&", right: 3},
+		{in: "6 | 3", left: 6, operator: "|", right: 3},
+		{in: "6 ^ 3", left: 6, operator: "^", right: 3},
+		{in: "1 << 4", left: 1, operator: "<<", right: 4},
+		{in: "16 >> 2", left: 16, operator: ">>", right: 2},
 	}
 
 	for _, test := range tests {
 		p, statements := parseStatementsWithLen(t, test.in, 1)
-		require.Len(t, p.errors, 0)
+		require.Len(t, p.Errors(), 0)
 
 		st, ok := statements[0].(*ast.ExpressionStatement)
 		require.True(t, ok)
@@ -197,7 +340,7 @@ func Test_InfixExpressionSimple(t *testing.T) {
 
 func Test_InfixSum(t *testing.T) {
 	p, statements := parseStatementsWithLen(t, "1 + 2 + 3", 1)
-	require.Len(t, p.errors, 0)
+	require.Len(t, p.Errors(), 0)
 
 	st, ok := statements[0].(*ast.ExpressionStatement)
 	require.True(t, ok)
@@ -229,16 +372,95 @@ func Test_InfixExpressionMultiple(t *testing.T) {
 		{in: "1 + 2 * 3", out: "(1 + (2 * 3))"},
 		{in: "1 < 2 * 3", out: "(1 < (2 * 3))"},
 		{in: "1 / 2 * 3", out: "((1 / 2) * 3)"},
+		{in: "a || b && c", out: "(a || (b && c))"},
+		{in: "a && b || c", out: "((a && b) || c)"},
+		{in: "a || b || c", out: "((a || b) || c)"},
+		{in: "a == 1 && b < 2 || !c", out: "(((a == 1) && (b < 2)) || (!c))"},
+		{in: "a + 1 != b * 2", out: "((a + 1) != (b * 2))"},
+		{in: "a % b * c", out: "((a % b) * c)"},
+		{in: "a + b % c", out: "(a + (b % c))"},
+		{in: "a <= b == c >= d", out: "((a <= b) == (c >= d))"},
+		{in: "a + 1 <= b * 2", out: "((a + 1) <= (b * 2))"},
+		// exponentiation is right-associative and binds tighter than prefix operators
+		{in: "2 ** 3 ** 2", out: "(2 ** (3 ** 2))"},
+		{in: "2 * 3 ** 2", out: "(2 * (3 ** 2))"},
+		{in: "2 ** 3 * 2", out: "((2 ** 3) * 2)"},
+		{in: "-2 ** 2", out: "(-(2 ** 2))"},
+		{in: "2 ** -2", out: "(2 ** (-2))"},
+		{in: "a[0] ** f(1)", out: "((a[0]) ** f(1))"},
+		{in: "a | b ^ c & d", out: "(a | (b ^ (c & d)))"},
+		{in: "a & b ^ c | d", out: "(((a & b) ^ c) | d)"},
+		{in: "a & b == c", out: "((a & b) == c)"},
+		{in: "a | b < c", out: "((a | b) < c)"},
+		{in: "a & b << 1 + c", out: "(a & (b << (1 + c)))"},
+		{in: "a << b >> c", out: "((a << b) >> c)"},
+		{in: "a | b && c", out: "((a | b) && c)"},
+		{in: "~a & b", out: "((~a) & b)"},
+		{in: "~a ** 2", out: "(~(a ** 2))"},
 	}
 
 	for _, test := range tests {
 		p, statements := parseStatementsWithLen(t, test.in, 1)
-		require.Len(t, p.errors, 0)
+		require.Len(t, p.Errors(), 0)
 
 		assert.Equal(t, test.out, statements[0].String())
 	}
 }
 
+func Test_AssignExpression(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "x = 5", out: "(x = 5)"},
+		{in: "x += 1 * 2", out: "(x += (1 * 2))"},
+		{in: "x -= 1", out: "(x -= 1)"},
+		{in: "x *= 2", out: "(x *= 2)"},
+		{in: "x /= 2", out: "(x /= 2)"},
+		{in: "a = b = 1", out: "(a = (b = 1))"},
+		{in: "x = a || b && c", out: "(x = (a || (b && c)))"},
+		{in: "arr[0] = 5", out: "((arr[0]) = 5)"},
+		{in: `m["a"][i + 1] += 2`, out: `(((m["a"])[(i + 1)]) += 2)`},
+		{in: "f(x = 1)", out: "f((x = 1))"},
+	}
+
+	for _, test := range tests {
+		p, statements := parseStatementsWithLen(t, test.in, 1)
+		require.Len(t, p.Errors(), 0)
+
+		assert.Equal(t, test.out, statements[0].String())
+	}
+
+	_, statements := parseStatementsWithLen(t, "m[0][1] = 2", 1)
+	assign, ok := statements[0].(*ast.ExpressionStatement).Expression.(*ast.AssignExpression)
+	require.True(t, ok)
+	assert.Equal(t, "", assign.InfixOperator())
+
+	variable, indices := assign.Variable()
+	assert.Equal(t, "m", variable.Value)
+	require.Len(t, indices, 2)
+	assertIntegerLiteral(t, indices[0], 0)
+	assertIntegerLiteral(t, indices[1], 1)
+}
+
+func Test_AssignExpressionErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		err string
+	}{
+		{in: "1 = 2", err: "1:3: cannot assign to 1"},
+		{in: "a + b = 1", err: "1:7: cannot assign to (a + b)"},
+		{in: "f()[0] = 1", err: "1:8: cannot assign to (f()[0])"},
+		{in: "a || b += 1", err: "1:8: cannot assign to (a || b)"},
+	}
+
+	for _, test := range tests {
+		_, errs := parseWithErrors(t, test.in)
+		require.Len(t, errs, 1)
+		assert.Equal(t, test.err, fmt.Sprintf("%s: %s", errs[0].Pos(), errs[0]))
+	}
+}
+
 func Test_GroupExpression(t *testing.T) {
 	tests := []struct {
 		in  string
@@ -253,7 +475,456 @@ func Test_GroupExpression(t *testing.T) {
 
 	for _, test := range tests {
 		p, statements := parseStatementsWithLen(t, test.in, 1)
-		require.Len(t, p.errors, 0)
+		require.Len(t, p.Errors(), 0)
+		assert.Equal(t, test.out, statements[0].String())
+	}
+}
+
+func Test_FunctionLiteral(t *testing.T) {
+	tests := []struct {
+		in     string
+		params []string
+		out    string
+	}{
+		{in: "fun(a, b) { a + b }", params: []string{"a", "b"}, out: "fun(a, b) { (a + b) }"},
+		{in: "fun(x) { x; }", params: []string{"x"}, out: "fun(x) { x }"},
+		{in: "fun() { 1 }", params: []string{}, out: "fun() { 1 }"},
+		{in: "fun(a) { let b = a; b * 2 }", params: []string{"a"}, out: "fun(a) { let b = a; (b * 2) }"},
+	}
+
+	for _, test := range tests {
+		p, statements := parseStatementsWithLen(t, test.in, 1)
+		require.Len(t, p.Errors(), 0)
+
+		st, ok := statements[0].(*ast.ExpressionStatement)
+		require.True(t, ok)
+
+		fun, ok := st.Expression.(*ast.FunctionLiteral)
+		require.True(t, ok)
+
+		params := make([]string, len(fun.Parameters))
+		for i, param := range fun.Parameters {
+			params[i] = param.Value
+		}
+
+		assert.Equal(t, test.params, params)
+		assert.Equal(t, test.out, fun.String())
+	}
+}
+
+func Test_CallExpression(t *testing.T) {
+	p, statements := parseStatementsWithLen(t, "add(1, 2 * 3, 4 + 5)", 1)
+	require.Len(t, p.Errors(), 0)
+
+	st, ok := statements[0].(*ast.ExpressionStatement)
+	require.True(t, ok)
+
+	call, ok := st.Expression.(*ast.CallExpression)
+	require.True(t, ok)
+
+	assert.Equal(t, "add", call.Function.String())
+	require.Len(t, call.Arguments, 3)
+	assertIntegerLiteral(t, call.Arguments[0], 1)
+	assert.Equal(t, "(2 * 3)", call.Arguments[1].String())
+	assert.Equal(t, "(4 + 5)", call.Arguments[2].String())
+}
+
+func Test_CallExpressionPrecedence(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "a + add(b * c) + d", out: "((a + add((b * c))) + d)"},
+		{in: "add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", out: "add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))"},
+		{in: "add(a + b + c * d / f + g)", out: "add((((a + b) + ((c * d) / f)) + g))"},
+		{in: "fun(x) { x }(5)", out: "fun(x) { x }(5)"},
+		{in: "-add()", out: "(-add())"},
+	}
+
+	for _, test := range tests {
+		p, statements := parseStatementsWithLen(t, test.in, 1)
+		require.Len(t, p.Errors(), 0)
 		assert.Equal(t, test.out, statements[0].String())
 	}
 }
+
+func Test_IfExpression(t *testing.T) {
+	tests := []struct {
+		in          string
+		condition   string
+		consequence string
+		alternative string
+		out         string
+	}{
+		{
+			in:          "if (x > 1) { 10 }",
+			condition:   "(x > 1)",
+			consequence: "{ 10 }",
+			out:         "if (x > 1) { 10 }",
+		},
+		{
+			in:          "if (x > 1) { 10 } else { 20 }",
+			condition:   "(x > 1)",
+			consequence: "{ 10 }",
+			alternative: "{ 20 }",
+			out:         "if (x > 1) { 10 } else { 20 }",
+		},
+		{
+			in:          "if x { let y = x; y }",
+			condition:   "x",
+			consequence: "{ let y = x; y }",
+			out:         "if x { let y = x; y }",
+		},
+		{
+			in:          "if (a) { 1 } else if (b) { 2 } else { 3 }",
+			condition:   "a",
+			consequence: "{ 1 }",
+			alternative: "{ if b { 2 } else { 3 } }",
+			out:         "if a { 1 } else { if b { 2 } else { 3 } }",
+		},
+	}
+
+	for _, test := range tests {
+		p, statements := parseStatementsWithLen(t, test.in, 1)
+		require.Len(t, p.Errors(), 0)
+
+		st, ok := statements[0].(*ast.ExpressionStatement)
+		require.True(t, ok)
+
+		exp, ok := st.Expression.(*ast.IfExpression)
+		require.True(t, ok)
+
+		assert.Equal(t, test.condition, exp.Condition.String())
+		assert.Equal(t, test.consequence, exp.Consequence.String())
+		if test.alternative == "" {
+			assert.Nil(t, exp.Alternative)
+		} else {
+			require.NotNil(t, exp.Alternative)
+			assert.Equal(t, test.alternative, exp.Alternative.String())
+		}
+		assert.Equal(t, test.out, exp.String())
+	}
+}
+
+func Test_NodePositions(t *testing.T) {
+	input := "let add = fun(a, b) {\n  a + b\n};\nadd(1, 2);"
+	p, statements := parseStatementsWithLen(t, input, 2)
+	require.Len(t, p.Errors(), 0)
+
+	span := func(node ast.Node) string {
+		return fmt.Sprintf("%s-%s", node.Pos(), node.End())
+	}
+
+	let, ok := statements[0].(*ast.LetStatement)
+	require.True(t, ok)
+	assert.Equal(t, "1:1-3:2", span(let))
+	assert.Equal(t, "1:5-1:8", span(&let.Identifier))
+
+	fun, ok := let.Value.(*ast.FunctionLiteral)
+	require.True(t, ok)
+	assert.Equal(t, "1:11-3:2", span(fun))
+	assert.Equal(t, "1:15-1:16", span(fun.Parameters[0]))
+	assert.Equal(t, "2:3-2:8", span(fun.Body.Statements[0]))
+
+	st, ok := statements[1].(*ast.ExpressionStatement)
+	require.True(t, ok)
+	assert.Equal(t, "4:1-4:10", span(st))
+
+	call, ok := st.Expression.(*ast.CallExpression)
+	require.True(t, ok)
+	assert.Equal(t, "4:5-4:6", span(call.Arguments[0]))
+	assert.Equal(t, 42, call.End().Offset)
+}
+
+func Test_ParseErrors(t *testing.T) {
+	_, errs := parseWithErrors(t, "let x 5;\n(1 + 2;")
+	require.Len(t, errs, 2)
+
+	assert.Equal(t, tokens.Token{
+		Literal: "5",
+		Type:    tokens.INT,
+		Pos:     tokens.Position{Line: 1, Column: 7, Offset: 6},
+		End:     tokens.Position{Line: 1, Column: 8, Offset: 7},
+	}, errs[0].Token)
+	assert.Equal(t, []tokens.TokenType{tokens.ASSIGN}, errs[0].Expected)
+	assert.Equal(t, "let statement", errs[0].Context)
+	assert.Equal(t, "parsing let statement failed: expected =, got INT", errs[0].Error())
+
+	assert.Equal(t, []tokens.TokenType{tokens.RPAREN}, errs[1].Expected)
+	assert.Equal(t, "", errs[1].Context)
+	assert.Equal(t, "2:7", errs[1].Pos().String())
+
+	assert.Equal(
+		t,
+		"1:7: parsing let statement failed: expected =, got INT\n2:7: expected ), got ;",
+		errs.Error(),
+	)
+	assert.Len(t, errs.Unwrap(), 2)
+}
+
+func Test_ErrorRecovery(t *testing.T) {
+	tests := []struct {
+		in         string
+		statements []string
+		errors     []string
+	}{
+		{
+			in:         "let = 1; let y = 2; y;",
+			statements: []string{"let y = 2;", "y"},
+			errors:     []string{"1:5: parsing let statement failed: expected IDENTIFIER, got ="},
+		},
+		{
+			in:         "let f = fun() { 1 + }; let z = 3;",
+			statements: []string{"let f = fun() {  };", "let z = 3;"},
+			errors:     []string{"1:21: no prefix parser found for }"},
+		},
+		{
+			in:         "let f = fun() { let = 1; 2 }; f();",
+			statements: []string{"let f = fun() { 2 };", "f()"},
+			errors:     []string{"1:21: parsing let statement failed: expected IDENTIFIER, got ="},
+		},
+		{
+			in:         "} let a = 1;",
+			statements: []string{"let a = 1;"},
+			errors:     []string{"1:1: no prefix parser found for }"},
+		},
+		{
+			in:         "add(1, 2; let a = 1;",
+			statements: []string{"let a = 1;"},
+			errors:     []string{"1:9: expected ), got ;"},
+		},
+		{
+			in:         "fun(a b) { a; b }; 1;",
+			statements: []string{"1"},
+			errors:     []string{"1:7: expected ), got IDENTIFIER"},
+		},
+		{
+			in:         "fun() { 1",
+			statements: []string{},
+			errors:     []string{"1:10: expected }, got EOF"},
+		},
+		{
+			in:         "let = 1;\nlet b 2;\nlet c = 3;\n1 + * 2;",
+			statements: []string{"let c = 3;"},
+			errors: []string{
+				"1:5: parsing let statement failed: expected IDENTIFIER, got =",
+				"2:7: parsing let statement failed: expected =, got INT",
+				"4:5: no prefix parser found for *",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		program, errs := parseWithErrors(t, test.in)
+
+		statements := make([]string, len(program.Statements))
+		for i, st := range program.Statements {
+			statements[i] = st.String()
+		}
+		assert.Equal(t, test.statements, statements, test.in)
+
+		errors := make([]string, len(errs))
+		for i, err := range errs {
+			errors[i] = fmt.Sprintf("%s: %s", err.Pos(), err)
+		}
+		assert.Equal(t, test.errors, errors, test.in)
+	}
+}
+
+func Test_ParseTerminatesAtEOF(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "let x = 1", out: "let x = 1;"},
+		{in: "5", out: "5"},
+		{in: "return", out: "return;"},
+	}
+
+	for _, test := range tests {
+		p, statements := parseStatementsWithLen(t, test.in, 1)
+		require.Len(t, p.Errors(), 0)
+		assert.Equal(t, test.out, statements[0].String())
+	}
+}
+
+func Test_StringLiteral(t *testing.T) {
+	p, statements := parseStatementsWithLen(t, `"hello\tworld" + "!"`, 1)
+	require.Len(t, p.Errors(), 0)
+
+	st, ok := statements[0].(*ast.ExpressionStatement)
+	require.True(t, ok)
+
+	exp, ok := st.Expression.(*ast.InfixExpression)
+	require.True(t, ok)
+
+	str, ok := exp.Left.(*ast.StringLiteral)
+	require.True(t, ok)
+	assert.Equal(t, "hello\tworld", str.Value)
+	assert.Equal(t, `("hello\tworld" + "!")`, st.String())
+}
+
+func Test_StringErrors(t *testing.T) {
+	_, errs := parseWithErrors(t, "let a = \"x\\q\";\nlet b = \"abc")
+	require.Len(t, errs, 2)
+
+	assert.Equal(t, "1:11: invalid escape sequence \\q in string", fmt.Sprintf("%s: %s", errs[0].Pos(), errs[0]))
+	assert.Equal(t, "2:9: unterminated string literal", fmt.Sprintf("%s: %s", errs[1].Pos(), errs[1]))
+
+	// errors inside the string are reported where the lexer found them
+	_, errs = parseWithErrors(t, "puts(\"abc\\qdef\");\nputs(\"ab\xffc\");")
+	require.Len(t, errs, 2)
+	assert.Equal(t, "1:10: invalid escape sequence \\q in string", fmt.Sprintf("%s: %s", errs[0].Pos(), errs[0]))
+	assert.Equal(t, "2:9: invalid UTF-8 encoding", fmt.Sprintf("%s: %s", errs[1].Pos(), errs[1]))
+}
+
+func Test_ArrayLiteral(t *testing.T) {
+	p, statements := parseStatementsWithLen(t, "[1, 2 * 2, 3 + 3]", 1)
+	require.Len(t, p.Errors(), 0)
+
+	st, ok := statements[0].(*ast.ExpressionStatement)
+	require.True(t, ok)
+
+	array, ok := st.Expression.(*ast.ArrayLiteral)
+	require.True(t, ok)
+
+	require.Len(t, array.Elements, 3)
+	assertIntegerLiteral(t, array.Elements[0], 1)
+	assert.Equal(t, "(2 * 2)", array.Elements[1].String())
+	assert.Equal(t, "(3 + 3)", array.Elements[2].String())
+	assert.Equal(t, "1:1-1:18", fmt.Sprintf("%s-%s", array.Pos(), array.End()))
+
+	p, statements = parseStatementsWithLen(t, "[]", 1)
+	require.Len(t, p.Errors(), 0)
+	assert.Equal(t, "[]", statements[0].String())
+}
+
+func Test_IndexExpression(t *testing.T) {
+	p, statements := parseStatementsWithLen(t, "array[1 + 1]", 1)
+	require.Len(t, p.Errors(), 0)
+
+	st, ok := statements[0].(*ast.ExpressionStatement)
+	require.True(t, ok)
+
+	exp, ok := st.Expression.(*ast.IndexExpression)
+	require.True(t, ok)
+
+	assert.Equal(t, "array", exp.Left.String())
+	assert.Equal(t, "(1 + 1)", exp.Index.String())
+	assert.Equal(t, "1:1-1:13", fmt.Sprintf("%s-%s", exp.Pos(), exp.End()))
+}
+
+func Test_IndexExpressionPrecedence(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "a * [1, 2, 3, 4][b * c] * d", out: "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
+		{in: "add(a * b[2], b[1], 2 * [1, 2][1])", out: "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+		{in: "-a[0]", out: "(-(a[0]))"},
+		{in: "a[0][1]", out: "((a[0])[1])"},
+		{in: "f(x)[0]", out: "(f(x)[0])"},
+	}
+
+	for _, test := range tests {
+		p, statements := parseStatementsWithLen(t, test.in, 1)
+		require.Len(t, p.Errors(), 0)
+		assert.Equal(t, test.out, statements[0].String())
+	}
+}
+
+func Test_HashLiteral(t *testing.T) {
+	tests := []struct {
+		in   string
+		keys []string
+		out  string
+	}{
+		{in: `{"name": "x", 1: true}`, keys: []string{`"name"`, "1"}, out: `{"name": "x", 1: true}`},
+		{in: `{}`, keys: []string{}, out: `{}`},
+		{in: `{"a": 1 + 2, b: fun(x) { x },}`, keys: []string{`"a"`, "b"}, out: `{"a": (1 + 2), b: fun(x) { x }}`},
+		{in: `let h = {true: [1]}`, keys: []string{"true"}, out: `{true: [1]}`},
+		{in: `if (x) { {1: 2} }`, keys: []string{"1"}, out: `{1: 2}`},
+	}
+
+	for _, test := range tests {
+		p, statements := parseStatementsWithLen(t, test.in, 1)
+		require.Len(t, p.Errors(), 0)
+
+		var hash *ast.HashLiteral
+		switch st := statements[0].(type) {
+		case *ast.ExpressionStatement:
+			if exp, ok := st.Expression.(*ast.IfExpression); ok {
+				hash = exp.Consequence.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.HashLiteral)
+			} else {
+				hash = st.Expression.(*ast.HashLiteral)
+			}
+		case *ast.LetStatement:
+			hash = st.Value.(*ast.HashLiteral)
+		}
+		require.NotNil(t, hash)
+
+		keys := make([]string, len(hash.Pairs))
+		for i, pair := range hash.Pairs {
+			keys[i] = pair.Key.String()
+		}
+		assert.Equal(t, test.keys, keys)
+		assert.Equal(t, test.out, hash.String())
+	}
+}
+
+func Test_HashLiteralErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		err string
+	}{
+		{in: `{"a" 1}`, err: "1:6: expected :, got INT"},
+		{in: `{"a": 1 "b": 2}`, err: "1:9: expected ,, got STRING"},
+		{in: `{"a": 1`, err: "1:8: expected ,, got EOF"},
+	}
+
+	for _, test := range tests {
+		_, errs := parseWithErrors(t, test.in)
+		require.Len(t, errs, 1)
+		assert.Equal(t, test.err, fmt.Sprintf("%s: %s", errs[0].Pos(), errs[0]))
+	}
+}
+
+func Test_Comments(t *testing.T) {
+	input := `// adds the numbers
+let add = fun(a, /* b */ b) {
+    a + b // sum
+};
+/* call */ add(1, 2)`
+
+	for _, l := range []*lexer.Lexer{lexer.New(input), lexer.New(input, lexer.WithComments())} {
+		program, err := New(l).Parse()
+		require.NoError(t, err)
+		assert.Equal(t, "let add = fun(a, b) { (a + b) };add(1, 2)", program.String())
+	}
+
+	_, errs := parseWithErrors(t, "let a = 1;\n/* unterminated")
+	require.Len(t, errs, 1)
+	assert.Equal(t, "2:1: unterminated block comment", fmt.Sprintf("%s: %s", errs[0].Pos(), errs[0]))
+}
+
+func Test_UnicodeIdentifiers(t *testing.T) {
+	_, statements := parseStatementsWithLen(t, "let größe_2 = 1; größe_2", 2)
+	assert.Equal(t, "größe_2", statements[0].(*ast.LetStatement).Identifier.Value)
+
+	_, errs := parseWithErrors(t, "let a = \xff;")
+	require.Len(t, errs, 1)
+	assert.Equal(t, "1:9: invalid UTF-8 encoding", fmt.Sprintf("%s: %s", errs[0].Pos(), errs[0]))
+}
+
+func Test_UnexpectedCharacters(t *testing.T) {
+	program, errs := parseWithErrors(t, "let x = 1 @ 2;\nlet y = $;\nputs(x # y);\nlet z = 3;")
+	require.Len(t, errs, 3)
+
+	assert.Equal(t, "1:11: unexpected character '@'", fmt.Sprintf("%s: %s", errs[0].Pos(), errs[0]))
+	assert.Equal(t, "2:9: unexpected character '$'", fmt.Sprintf("%s: %s", errs[1].Pos(), errs[1]))
+	assert.Equal(t, "3:8: unexpected character '#'", fmt.Sprintf("%s: %s", errs[2].Pos(), errs[2]))
+	assert.Equal(t, []tokens.TokenType{tokens.RPAREN}, errs[2].Expected)
+
+	// parsing continues after the invalid characters
+	assert.Equal(t, "let z = 3;", program.Statements[len(program.Statements)-1].String())
+}
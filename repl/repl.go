@@ -0,0 +1,232 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"language/ast"
+	"language/diagnostics"
+	"language/evaluator"
+	"language/lexer"
+	"language/object"
+	"language/parser"
+	"language/tokens"
+	"strconv"
+	"strings"
+)
+
+const (
+	PROMPT          = ">> "
+	CONTINUE_PROMPT = ".. "
+)
+
+const help = `:help             show this help
+:tokens <input>   print the tokens produced by the lexer
+:ast <input>      print the program produced by the parser
+:history          print previously entered inputs
+:quit             exit the repl`
+
+type REPL struct {
+	scanner     *bufio.Scanner
+	out         io.Writer
+	env         *object.Environment
+	history     []string
+	bigIntegers bool
+
+	// sources holds the parsed inputs, their positions have the index of
+	// the input as file name, so errors raised by functions defined in an
+	// earlier input are shown with the line they come from
+	sources []string
+}
+
+type Option func(*REPL)
+
+// WithBigIntegers uses arbitrary-precision integers, both for literals and
+// for results, instead of wrapping around on overflow.
+func WithBigIntegers() Option {
+	return func(r *REPL) {
+		r.bigIntegers = true
+	}
+}
+
+func New(in io.Reader, out io.Writer, opts ...Option) *REPL {
+	r := &REPL{
+		scanner: bufio.NewScanner(in),
+		out:     out,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.env = object.NewEnvironmentWithRuntime(&object.Runtime{Stdout: out, BigIntegers: r.bigIntegers})
+	return r
+}
+
+// Start reads inputs until the reader is exhausted or the quit command is
+// entered, bindings are kept in the same environment between inputs.
+func Start(in io.Reader, out io.Writer, opts ...Option) {
+	New(in, out, opts...).Run()
+}
+
+func (r *REPL) Run() {
+	for {
+		input, ok := r.readInput()
+		if !ok {
+			return
+		}
+		if strings.TrimSpace(input) == "" {
+			continue
+		}
+
+		r.history = append(r.history, input)
+		if !r.execute(input) {
+			return
+		}
+	}
+}
+
+// readInput reads lines until all the braces and parentheses are closed,
+// so functions and blocks can be written over multiple lines.
+func (r *REPL) readInput() (string, bool) {
+	var input strings.Builder
+	prompt := PROMPT
+
+	for {
+		fmt.Fprint(r.out, prompt)
+		if !r.scanner.Scan() {
+			return input.String(), input.Len() > 0
+		}
+
+		input.WriteString(r.scanner.Text())
+		if isBalanced(source(input.String())) {
+			return input.String(), true
+		}
+
+		input.WriteString("\n")
+		prompt = CONTINUE_PROMPT
+	}
+}
+
+// execute runs a command or evaluates the input, it returns false when
+// the repl should exit.
+func (r *REPL) execute(input string) bool {
+	if !strings.HasPrefix(input, ":") {
+		r.eval(input)
+		return true
+	}
+
+	command, _, _ := strings.Cut(input, " ")
+	arg := source(input)
+
+	switch command {
+	case ":help":
+		fmt.Fprintln(r.out, help)
+	case ":tokens":
+		r.printTokens(arg)
+	case ":ast":
+		r.printAST(arg)
+	case ":history":
+		for i, entry := range r.history[:len(r.history)-1] {
+			fmt.Fprintf(r.out, "%3d  %s\n", i+1, strings.ReplaceAll(entry, "\n", "\n     "))
+		}
+	case ":quit", ":exit":
+		return false
+	default:
+		fmt.Fprintf(r.out, "unknown command %s, type :help for a list of commands\n", command)
+	}
+
+	return true
+}
+
+func (r *REPL) eval(input string) {
+	program, ok := r.parse(input)
+	if !ok {
+		return
+	}
+
+	switch result := evaluator.Eval(program, r.env).(type) {
+	case nil:
+	case *object.Error:
+		fmt.Fprintln(r.out, r.format(result.Pos, result.Message))
+	default:
+		fmt.Fprintln(r.out, result)
+	}
+}
+
+func (r *REPL) parse(input string) (*ast.Program, bool) {
+	var opts []parser.Option
+	if r.bigIntegers {
+		opts = append(opts, parser.WithBigIntegers())
+	}
+
+	file := strconv.Itoa(len(r.sources))
+	r.sources = append(r.sources, input)
+
+	p := parser.New(lexer.New(input, lexer.WithFile(file)), opts...)
+	program, err := p.Parse()
+	if err != nil {
+		for _, err := range p.Errors() {
+			fmt.Fprintln(r.out, r.format(err.Pos(), err.Error()))
+		}
+		return nil, false
+	}
+
+	return program, true
+}
+
+// format renders the message with the source line of the input the
+// position is in, the file name used to find the input is left out.
+func (r *REPL) format(pos tokens.Position, message string) string {
+	var source string
+	if i, err := strconv.Atoi(pos.File); err == nil && i < len(r.sources) {
+		source = r.sources[i]
+	}
+
+	pos.File = ""
+	return diagnostics.Format(source, pos, message)
+}
+
+func (r *REPL) printTokens(input string) {
+	l := lexer.New(input, lexer.WithComments())
+	for token := l.NextToken(); token.Type != tokens.EOF; token = l.NextToken() {
+		fmt.Fprintf(r.out, "%-6s %-10s %q\n", token.Pos, token.Type, token.Literal)
+	}
+}
+
+func (r *REPL) printAST(input string) {
+	program, ok := r.parse(input)
+	if !ok {
+		return
+	}
+
+	for _, st := range program.Statements {
+		pos := st.Pos()
+		pos.File = ""
+		fmt.Fprintf(r.out, "%-6s %T %s\n", pos, st, st)
+	}
+}
+
+// source returns the input without the command, if it has one.
+func source(input string) string {
+	if !strings.HasPrefix(input, ":") {
+		return input
+	}
+
+	_, arg, _ := strings.Cut(input, " ")
+	return arg
+}
+
+func isBalanced(input string) bool {
+	depth := 0
+	l := lexer.New(input)
+	for token := l.NextToken(); token.Type != tokens.EOF; token = l.NextToken() {
+		switch token.Type {
+		case tokens.LBRACE, tokens.LPAREN, tokens.LBRACKET:
+			depth++
+		case tokens.RBRACE, tokens.RPAREN, tokens.RBRACKET:
+			depth--
+		}
+	}
+
+	return depth <= 0
+}
@@ -0,0 +1,79 @@
+package repl
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func runInput(input string) string {
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+	return out.String()
+}
+
+func Test_Eval(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "1 + 2\n", out: ">> 3\n>> "},
+		{in: "let x = 2;\nx * 3\n", out: ">> >> 6\n>> "},
+		{in: "foo\n", out: ">> 1:1: identifier not found: foo\n    foo\n    ^\n>> "},
+		{in: "let f = fun() { f() };\nf()\n1\n", out: ">> >> 1:17: stack overflow\n    let f = fun() { f() };\n                    ^\n>> 1\n>> "},
+		{in: "\n  \n1\n", out: ">> >> >> 1\n>> "},
+		{in: "puts(1 + 2)\n", out: ">> 3\nnull\n>> "},
+		{
+			in:  "let x 5;\n",
+			out: ">> 1:7: parsing let statement failed: expected =, got INT\n    let x 5;\n          ^\n>> ",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.out, runInput(test.in), test.in)
+	}
+}
+
+func Test_MultilineInput(t *testing.T) {
+	input := "let add = fun(a, b) {\n  a + b\n};\nadd(\n1, 2)\n"
+	assert.Equal(t, ">> .. .. >> .. 3\n>> ", runInput(input))
+
+	input = "[1,\n2][1]\n"
+	assert.Equal(t, ">> .. 2\n>> ", runInput(input))
+}
+
+func Test_Commands(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{
+			in:  ":tokens let x = 1;\n",
+			out: ">> 1:1    LET        \"let\"\n1:5    IDENTIFIER \"x\"\n1:7    =          \"=\"\n1:9    INT        \"1\"\n1:10   ;          \";\"\n>> ",
+		},
+		{
+			in:  ":ast 1 + 2 * 3; let y = fun(x) {\nx }\n",
+			out: ">> .. 1:1    *ast.ExpressionStatement (1 + (2 * 3))\n1:12   *ast.LetStatement let y = fun(x) { x };\n>> ",
+		},
+		{
+			in:  "1\n2\n:history\n",
+			out: ">> 1\n>> 2\n>>   1  1\n  2  2\n>> ",
+		},
+		{in: ":quit\n1\n", out: ">> "},
+		{in: ":foo\n", out: ">> unknown command :foo, type :help for a list of commands\n>> "},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.out, runInput(test.in), test.in)
+	}
+}
+
+func Test_BigIntegers(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader("let a = 99999999999999999999;\na + 1\n"), &out, WithBigIntegers())
+	assert.Equal(t, ">> >> 100000000000000000000\n>> ", out.String())
+
+	// without the option the literal doesn't parse
+	assert.Contains(t, runInput("99999999999999999999\n"), "integer literal 99999999999999999999 out of range")
+}
@@ -1,29 +1,53 @@
 package tokens
 
+import "fmt"
+
 type TokenType string
 
 const (
-	IDENTIFIER = "IDENTIFIER"
-	INT        = "INT"
-	SEMICOLON  = ";"
-	ASSIGN     = "="
-	PLUS       = "+"
-	MINUS      = "-"
-	MULTIPLY   = "*"
-	DIVIDE     = "/"
-	EQUAL      = "=="
-	NOTEQUAL   = "!="
-	LESS       = "<"
-	GREATER    = ">"
-	LPAREN     = "("
-	RPAREN     = ")"
-	BANG       = "!"
-	LBRACE     = "{"
-	RBRACE     = "}"
-	COMMA      = ","
-	SPACE      = " "
-	EOF        = ""
-	INVALID    = "INVALID"
+	IDENTIFIER     = "IDENTIFIER"
+	INT            = "INT"
+	FLOAT          = "FLOAT"
+	STRING         = "STRING"
+	COMMENT        = "COMMENT"
+	SEMICOLON      = ";"
+	ASSIGN         = "="
+	PLUSASSIGN     = "+="
+	MINUSASSIGN    = "-="
+	MULTIPLYASSIGN = "*="
+	DIVIDEASSIGN   = "/="
+	PLUS           = "+"
+	MINUS          = "-"
+	MULTIPLY       = "*"
+	DIVIDE         = "/"
+	MODULO         = "%"
+	POWER          = "**"
+	EQUAL          = "=="
+	NOTEQUAL       = "!="
+	AND            = "&&"
+	OR             = "||"
+	LESS           = "<"
+	GREATER        = ">"
+	LESSEQUAL      = "<="
+	GREATEREQUAL   = ">="
+	BITAND         = "&"
+	BITOR          = "|"
+	BITXOR         = "^"
+	BITNOT         = "~"
+	SHIFTLEFT      = "<<"
+	SHIFTRIGHT     = ">>"
+	LPAREN         = "("
+	RPAREN         = ")"
+	BANG           = "!"
+	LBRACE         = "{"
+	RBRACE         = "}"
+	LBRACKET       = "["
+	RBRACKET       = "]"
+	COMMA          = ","
+	COLON          = ":"
+	SPACE          = " "
+	EOF            = ""
+	INVALID        = "INVALID"
 
 	// keywords
 	LET    = "LET"
@@ -31,6 +55,8 @@ const (
 	TRUE   = "TRUE"
 	FALSE  = "FALSE"
 	RETURN = "RETURN"
+	IF     = "IF"
+	ELSE   = "ELSE"
 )
 
 var EOFToken = Token{
@@ -44,11 +70,35 @@ var keywords = map[string]TokenType{
 	"true":   TRUE,
 	"false":  FALSE,
 	"return": RETURN,
+	"if":     IF,
+	"else":   ELSE,
+}
+
+// Position is a location in the source, Line and Column are 1-based,
+// Offset is the 0-based byte offset from the start of the input.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+	Offset int
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if p.File != "" {
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 type Token struct {
 	Literal string
 	Type    TokenType
+	Pos     Position // position of the first character
+	End     Position // position immediately after the last character
 }
 
 func New(literal string, t TokenType) Token {
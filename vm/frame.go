@@ -0,0 +1,26 @@
+package vm
+
+import (
+	"language/code"
+	"language/object"
+)
+
+// Frame holds the state of a function call, its locals are kept on the
+// stack starting at the base pointer.
+type Frame struct {
+	cl          *object.Closure
+	ip          int
+	basePointer int
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{
+		cl:          cl,
+		ip:          -1,
+		basePointer: basePointer,
+	}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
@@ -0,0 +1,446 @@
+package vm
+
+import (
+	"fmt"
+	"language/code"
+	"language/compiler"
+	"language/object"
+	"language/tokens"
+)
+
+const (
+	MaxFrames   = object.MaxCallDepth + 1 // the calls and the main program
+	FrameSize   = 32                      // stack slots reserved for the locals and operands of a call
+	StackSize   = MaxFrames * FrameSize
+	GlobalsSize = 65536
+)
+
+// Error is returned when the program fails at runtime, the messages are
+// the same as the ones produced by the evaluator.
+type Error struct {
+	Pos     tokens.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var infixOperators = map[code.Opcode]string{
+	code.OpAdd:          tokens.PLUS,
+	code.OpSub:          tokens.MINUS,
+	code.OpMul:          tokens.MULTIPLY,
+	code.OpDiv:          tokens.DIVIDE,
+	code.OpMod:          tokens.MODULO,
+	code.OpPow:          tokens.POWER,
+	code.OpBitAnd:       tokens.BITAND,
+	code.OpBitOr:        tokens.BITOR,
+	code.OpBitXor:       tokens.BITXOR,
+	code.OpShiftLeft:    tokens.SHIFTLEFT,
+	code.OpShiftRight:   tokens.SHIFTRIGHT,
+	code.OpEqual:        tokens.EQUAL,
+	code.OpNotEqual:     tokens.NOTEQUAL,
+	code.OpLessThan:     tokens.LESS,
+	code.OpGreaterThan:  tokens.GREATER,
+	code.OpLessEqual:    tokens.LESSEQUAL,
+	code.OpGreaterEqual: tokens.GREATEREQUAL,
+}
+
+type VM struct {
+	constants []object.Object
+	globals   []object.Object
+	runtime   *object.Runtime
+
+	stack []object.Object
+	sp    int // always points to the next free slot, the top of the stack is stack[sp-1]
+
+	frames      []*Frame
+	framesIndex int
+
+	openCells []openCell // cells of captured locals of running functions
+}
+
+// openCell is a cell that still refers to the stack slot of its variable.
+type openCell struct {
+	slot int
+	cell *object.Cell
+}
+
+type Option func(*VM)
+
+// WithRuntime runs the program with the given settings instead of the
+// defaults of object.NewRuntime.
+func WithRuntime(rt *object.Runtime) Option {
+	return func(vm *VM) {
+		vm.runtime = rt
+	}
+}
+
+func New(bytecode *compiler.Bytecode, opts ...Option) *VM {
+	return NewWithGlobalsStore(bytecode, make([]object.Object, GlobalsSize), opts...)
+}
+
+// NewWithGlobalsStore creates a virtual machine that keeps the globals of
+// a previous run, which is used by interactive sessions.
+func NewWithGlobalsStore(bytecode *compiler.Bytecode, globals []object.Object, opts ...Option) *VM {
+	mainFn := &object.CompiledFunction{
+		Instructions: bytecode.Instructions,
+		Positions:    bytecode.Positions,
+	}
+
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = NewFrame(&object.Closure{Fn: mainFn}, 0)
+
+	vm := &VM{
+		constants:   bytecode.Constants,
+		globals:     globals,
+		runtime:     object.NewRuntime(),
+		stack:       make([]object.Object, StackSize),
+		frames:      frames,
+		framesIndex: 1,
+	}
+	for _, opt := range opts {
+		opt(vm)
+	}
+
+	return vm
+}
+
+// LastPoppedStackElem returns the value of the last expression statement
+// executed, or the value returned from the program. It is nil when the
+// program ends with a let statement, like the result of the evaluator.
+func (vm *VM) LastPoppedStackElem() object.Object {
+	return vm.stack[vm.sp]
+}
+
+func (vm *VM) Run() error {
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		frame := vm.currentFrame()
+		frame.ip++
+
+		ip := frame.ip
+		ins := frame.Instructions()
+		op := code.Opcode(ins[ip])
+
+		var err *object.Error
+
+		switch op {
+		case code.OpConstant:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			err = vm.push(vm.constants[index])
+		case code.OpPop:
+			vm.pop()
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+			code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight,
+			code.OpEqual, code.OpNotEqual, code.OpLessThan, code.OpGreaterThan,
+			code.OpLessEqual, code.OpGreaterEqual:
+			right := vm.pop()
+			left := vm.pop()
+			err = vm.pushResult(vm.runtime.Infix(infixOperators[op], left, right))
+		case code.OpMinus:
+			err = vm.pushResult(vm.runtime.Prefix(tokens.MINUS, vm.pop()))
+		case code.OpBang:
+			err = vm.pushResult(vm.runtime.Prefix(tokens.BANG, vm.pop()))
+		case code.OpBitNot:
+			err = vm.pushResult(vm.runtime.Prefix(tokens.BITNOT, vm.pop()))
+		case code.OpTrue:
+			err = vm.push(object.TrueValue)
+		case code.OpFalse:
+			err = vm.push(object.FalseValue)
+		case code.OpNull:
+			err = vm.push(object.NullValue)
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip = pos - 1
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			if !object.IsTruthy(vm.pop()) {
+				frame.ip = pos - 1
+			}
+		case code.OpSetGlobal:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			vm.globals[index] = vm.pop()
+			vm.stack[vm.sp] = nil // a program ending with let has no value
+		case code.OpGetGlobal:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			err = vm.push(vm.globals[index])
+		case code.OpSetLocal:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			vm.stack[frame.basePointer+int(index)] = vm.pop()
+		case code.OpGetLocal:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			err = vm.push(vm.stack[frame.basePointer+int(index)])
+		case code.OpGetBuiltin:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			err = vm.push(object.Builtins[index])
+		case code.OpGetFree:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			err = vm.push(frame.cl.Free[index].Get())
+		case code.OpSetFree:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			frame.cl.Free[index].Set(vm.pop())
+		case code.OpCaptureLocal:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			err = vm.push(vm.captureLocal(frame.basePointer + int(index)))
+		case code.OpCaptureFree:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			err = vm.push(frame.cl.Free[index])
+		case code.OpArray:
+			count := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			err = vm.pushResult(vm.buildArray(count))
+		case code.OpHash:
+			count := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			err = vm.push(vm.buildHash(count))
+		case code.OpHashKey:
+			if key := vm.stack[vm.sp-1]; !isHashable(key) {
+				err = newError("unusable as hash key: %s", key.Type())
+			}
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			err = vm.pushResult(object.Index(left, index))
+		case code.OpSetIndex:
+			count := int(code.ReadUint8(ins[ip+1:]))
+			frame.ip += 1
+			err = vm.setIndex(count, "")
+		case code.OpUpdateIndex:
+			count := int(code.ReadUint8(ins[ip+1:]))
+			operator, ok := infixOperators[code.Opcode(ins[ip+2])]
+			frame.ip += 2
+			if !ok {
+				err = newError("unknown operator opcode %d", ins[ip+2])
+				break
+			}
+			err = vm.setIndex(count, operator)
+		case code.OpCall:
+			args := int(code.ReadUint8(ins[ip+1:]))
+			frame.ip += 1
+			err = vm.call(args)
+		case code.OpClosure:
+			index := code.ReadUint16(ins[ip+1:])
+			free := int(code.ReadUint8(ins[ip+3:]))
+			frame.ip += 3
+			err = vm.pushClosure(int(index), free)
+		case code.OpReturnValue:
+			if vm.framesIndex == 1 {
+				vm.pop() // the program returned, the value stays as last popped
+				return nil
+			}
+			value := vm.pop()
+			frame := vm.popFrame()
+			vm.closeCells(frame.basePointer)
+			vm.sp = frame.basePointer - 1
+			err = vm.push(value)
+		case code.OpReturn:
+			if vm.framesIndex == 1 {
+				vm.stack[vm.sp] = object.NullValue
+				return nil
+			}
+			frame := vm.popFrame()
+			vm.closeCells(frame.basePointer)
+			vm.sp = frame.basePointer - 1
+			err = vm.push(object.NullValue)
+		default:
+			err = newError("unknown opcode %d", op)
+		}
+
+		if err != nil {
+			return &Error{Pos: frame.cl.Fn.Positions[ip], Message: err.Message}
+		}
+	}
+
+	return nil
+}
+
+/*
+	Calls
+*/
+
+func (vm *VM) call(numArgs int) *object.Error {
+	switch callee := vm.stack[vm.sp-1-numArgs].(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		args := vm.stack[vm.sp-numArgs : vm.sp]
+		result := callee.Fn(vm.runtime, args...)
+		vm.sp = vm.sp - numArgs - 1
+
+		if result == nil {
+			return vm.push(object.NullValue)
+		}
+		return vm.pushResult(result)
+	default:
+		return newError("not a function: %s", callee.Type())
+	}
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) *object.Error {
+	if numArgs != cl.Fn.NumParameters {
+		return newError("wrong number of arguments: expected %d, got %d", cl.Fn.NumParameters, numArgs)
+	}
+
+	// the arguments already on the stack become the first locals, the
+	// room for the frame is checked here so an overflow is reported at the
+	// call instead of at an operand pushed by the function
+	frame := NewFrame(cl, vm.sp-numArgs)
+	if vm.framesIndex >= MaxFrames || frame.basePointer+cl.Fn.NumLocals+FrameSize > StackSize {
+		return newError("stack overflow")
+	}
+
+	vm.pushFrame(frame)
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+	return nil
+}
+
+func (vm *VM) pushClosure(index int, numFree int) *object.Error {
+	fn, ok := vm.constants[index].(*object.CompiledFunction)
+	if !ok {
+		return newError("not a function: %s", vm.constants[index].Type())
+	}
+
+	free := make([]*object.Cell, numFree)
+	for i, cell := range vm.stack[vm.sp-numFree : vm.sp] {
+		free[i] = cell.(*object.Cell)
+	}
+	vm.sp = vm.sp - numFree
+
+	return vm.push(&object.Closure{Fn: fn, Free: free})
+}
+
+// captureLocal returns the cell of the local variable in the stack slot,
+// all closures created by the same call share it.
+func (vm *VM) captureLocal(slot int) *object.Cell {
+	for _, open := range vm.openCells {
+		if open.slot == slot {
+			return open.cell
+		}
+	}
+
+	cell := object.NewCell(&vm.stack[slot])
+	vm.openCells = append(vm.openCells, openCell{slot: slot, cell: cell})
+	return cell
+}
+
+// closeCells detaches the cells of the locals of a returning function from
+// the stack, so the closures keep the variables after the slots are reused.
+func (vm *VM) closeCells(basePointer int) {
+	open := vm.openCells[:0]
+	for _, c := range vm.openCells {
+		if c.slot >= basePointer {
+			c.cell.Close()
+		} else {
+			open = append(open, c)
+		}
+	}
+	vm.openCells = open
+}
+
+/*
+	Collections
+*/
+
+func (vm *VM) buildArray(count int) object.Object {
+	elements := make([]object.Object, count)
+	copy(elements, vm.stack[vm.sp-count:vm.sp])
+	vm.sp = vm.sp - count
+
+	return &object.Array{Elements: elements}
+}
+
+// buildHash creates the hash from the keys and values on the stack, the
+// keys were checked by OpHashKey.
+func (vm *VM) buildHash(count int) *object.Hash {
+	hash := object.NewHash()
+	start := vm.sp - count
+
+	for i := start; i < vm.sp; i += 2 {
+		hash.Set(vm.stack[i].(object.Hashable), vm.stack[i+1])
+	}
+
+	vm.sp = start
+	return hash
+}
+
+// setIndex replaces the collection, the indices and the value on the stack
+// by the assigned value and the updated collection.
+func (vm *VM) setIndex(count int, operator string) *object.Error {
+	value := vm.pop()
+	indices := make([]object.Object, count)
+	copy(indices, vm.stack[vm.sp-count:vm.sp])
+	vm.sp = vm.sp - count
+	current := vm.pop()
+
+	updated, assigned := vm.runtime.Assign(current, indices, operator, value)
+	if err, ok := updated.(*object.Error); ok {
+		return err
+	}
+
+	if err := vm.push(assigned); err != nil {
+		return err
+	}
+	return vm.push(updated)
+}
+
+/*
+	Helpers
+*/
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) {
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+func (vm *VM) push(obj object.Object) *object.Error {
+	if vm.sp >= StackSize {
+		return newError("stack overflow")
+	}
+
+	vm.stack[vm.sp] = obj
+	vm.sp++
+	return nil
+}
+
+// pushResult pushes the result of an operation, unless it failed.
+func (vm *VM) pushResult(obj object.Object) *object.Error {
+	if err, ok := obj.(*object.Error); ok {
+		return err
+	}
+	return vm.push(obj)
+}
+
+func (vm *VM) pop() object.Object {
+	obj := vm.stack[vm.sp-1]
+	vm.sp--
+	return obj
+}
+
+func isHashable(obj object.Object) bool {
+	_, ok := obj.(object.Hashable)
+	return ok
+}
+
+func newError(format string, args ...any) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, args...)}
+}
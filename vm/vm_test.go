@@ -0,0 +1,273 @@
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"language/ast"
+	"language/compiler"
+	"language/evaluator"
+	"language/lexer"
+	"language/object"
+	"language/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parseInput(t testing.TB, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program, err := p.Parse()
+	require.NoError(t, err, fmt.Sprintf("parsing input %s failed", input))
+	return program
+}
+
+func runInput(t testing.TB, input string) (object.Object, error) {
+	c := compiler.New()
+	require.NoError(t, c.Compile(parseInput(t, input)), fmt.Sprintf("compiling input %s failed", input))
+
+	machine := New(c.Bytecode())
+	if err := machine.Run(); err != nil {
+		return nil, err
+	}
+	return machine.LastPoppedStackElem(), nil
+}
+
+func Test_Run(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{in: "1 + 2 * 3", out: "7"},
+		{in: "-(10 - 4) / 2", out: "-3"},
+		{in: "1 < 2 == !false", out: "true"},
+		{in: `"foo" + "bar"`, out: "foobar"},
+		{in: "if (1 > 2) { 10 }", out: "null"},
+		{in: "if (1 > 2) { 10 } else if (true) { 20 }", out: "20"},
+		{in: "let a = 1; let b = a + 1; let a = b * 3; a", out: "6"},
+		{in: "[1, 2 + 3, [4]][1]", out: "5"},
+		{in: `{"a": 1, 2: [3]}[2]`, out: "[3]"},
+		{in: `{"a": 1}["b"]`, out: "null"},
+		{in: "let add = fun(a, b) { a + b }; add(1, add(2, 3))", out: "6"},
+		{in: "let f = fun() { }; f()", out: "null"},
+		{in: "let f = fun(x) { if (x) { return 1; } return; }; [f(true), f(false)]", out: "[1, null]"},
+		{in: "fun() { let y = 5; let y = y + 1; y }()", out: "6"},
+		{in: "let adder = fun(a) { fun(b) { fun(c) { a + b + c } } }; adder(1)(2)(3)", out: "6"},
+		{in: "let countdown = fun(n) { if (n == 0) { return 0; } countdown(n - 1) }; countdown(10)", out: "0"},
+		{in: "let wrapper = fun() { let inner = fun(n) { if (n > 0) { inner(n - 1) } else { n } }; inner(3) }; wrapper()", out: "0"},
+		{in: "len(push([1], 2))", out: "2"},
+		{in: "first([])", out: "null"},
+		{in: "return 5; 10", out: "5"},
+		{in: "return; 10", out: "null"},
+	}
+
+	for _, test := range tests {
+		result, err := runInput(t, test.in)
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.out, result.String(), test.in)
+	}
+}
+
+func Test_RuntimeErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+		pos string
+	}{
+		{in: "5 + true;", out: "type mismatch: INTEGER + BOOLEAN", pos: "1:1"},
+		{in: "1;\n-true", out: "unknown operator: -BOOLEAN", pos: "2:1"},
+		{in: "let f = fun() { 1 / 0 };\nf()", out: "division by zero: 1 / 0", pos: "1:17"},
+		{in: "[1][5]", out: "index out of range: 5 with length 1", pos: "1:1"},
+		{in: "1(2)", out: "not a function: INTEGER", pos: "1:1"},
+		{in: "fun(a) { a }()", out: "wrong number of arguments: expected 1, got 0", pos: "1:1"},
+		{in: `len(1)`, out: "argument to len not supported, got INTEGER", pos: "1:1"},
+		{in: "{[1]: 2}", out: "unusable as hash key: ARRAY", pos: "1:2"},
+		{in: "let f = fun() { f() }; f()", out: "stack overflow", pos: "1:17"},
+		{in: "let f = fun(n) { 1 + f(n + 1) }; f(0)", out: "stack overflow", pos: "1:22"},
+	}
+
+	for _, test := range tests {
+		_, err := runInput(t, test.in)
+		require.Error(t, err, test.in)
+
+		runtimeErr, ok := err.(*Error)
+		require.True(t, ok, fmt.Sprintf("expected runtime error, got %T", err))
+		assert.Equal(t, test.out, runtimeErr.Message, test.in)
+		assert.Equal(t, test.pos, runtimeErr.Pos.String(), test.in)
+	}
+}
+
+func Test_GlobalsStore(t *testing.T) {
+	globals := make([]object.Object, GlobalsSize)
+
+	c := compiler.New()
+	require.NoError(t, c.Compile(parseInput(t, "let a = 2;")))
+	require.NoError(t, NewWithGlobalsStore(c.Bytecode(), globals).Run())
+
+	next := compiler.NewWithState(c.SymbolTable(), c.Bytecode().Constants)
+	require.NoError(t, next.Compile(parseInput(t, "a * 21")))
+
+	machine := NewWithGlobalsStore(next.Bytecode(), globals)
+	require.NoError(t, machine.Run())
+	assert.Equal(t, "42", machine.LastPoppedStackElem().String())
+}
+
+func Test_GlobalsStoreAfterCompileError(t *testing.T) {
+	globals := make([]object.Object, GlobalsSize)
+
+	c := compiler.New()
+	require.NoError(t, c.Compile(parseInput(t, "let g = 1;")))
+	require.NoError(t, NewWithGlobalsStore(c.Bytecode(), globals).Run())
+
+	// the functions fail to compile, so f stays undefined and g keeps its value
+	next := compiler.NewWithState(c.SymbolTable(), c.Bytecode().Constants)
+	require.Error(t, next.Compile(parseInput(t, "let f = fun() { missing };")))
+	require.Error(t, next.Compile(parseInput(t, "let g = fun() { missing };")))
+
+	next = compiler.NewWithState(c.SymbolTable(), c.Bytecode().Constants)
+	err := next.Compile(parseInput(t, "f()"))
+	require.Error(t, err)
+	assert.Equal(t, "identifier not found: f", err.Error())
+
+	next = compiler.NewWithState(c.SymbolTable(), c.Bytecode().Constants)
+	require.NoError(t, next.Compile(parseInput(t, "let h = 2; g + h")))
+
+	machine := NewWithGlobalsStore(next.Bytecode(), globals)
+	require.NoError(t, machine.Run())
+	assert.Equal(t, "3", machine.LastPoppedStackElem().String())
+}
+
+func Test_WithRuntime(t *testing.T) {
+	c := compiler.New()
+	require.NoError(t, c.Compile(parseInput(t, `puts("hello", 1 + 2)`)))
+
+	var out bytes.Buffer
+	require.NoError(t, New(c.Bytecode(), WithRuntime(&object.Runtime{Stdout: &out})).Run())
+	assert.Equal(t, "hello\n3\n", out.String())
+}
+
+// Test_Parity runs the programs the evaluator tests use, they must produce
+// the same result, or fail with the same error, in the virtual machine.
+func Test_Parity(t *testing.T) {
+	source, err := os.ReadFile(filepath.Join("..", "evaluator", "testdata", "programs.lang"))
+	require.NoError(t, err)
+
+	inputs := strings.Split(strings.TrimSpace(string(source)), "\n\n")
+	require.NotEmpty(t, inputs)
+
+	for _, input := range inputs {
+		assertParity(t, parseInput(t, input), input)
+	}
+}
+
+func assertParity(t *testing.T, program *ast.Program, input string) {
+	rt := &object.Runtime{Stdout: io.Discard}
+	expected := evaluator.Eval(program, object.NewEnvironmentWithRuntime(rt))
+
+	c := compiler.New()
+	if err := c.Compile(program); err != nil {
+		// names are resolved at compile time, the evaluator reports them
+		// when they are evaluated
+		expectedErr, ok := expected.(*object.Error)
+		require.True(t, ok, fmt.Sprintf("compiling %s failed: %s", input, err))
+		assert.Equal(t, expectedErr.Message, err.Error(), input)
+		return
+	}
+
+	machine := New(c.Bytecode(), WithRuntime(rt))
+	runErr := machine.Run()
+
+	if expectedErr, ok := expected.(*object.Error); ok {
+		require.Error(t, runErr, input)
+		assert.Equal(t, expectedErr.Message, runErr.Error(), input)
+		assert.Equal(t, expectedErr.Pos, runErr.(*Error).Pos, input)
+		return
+	}
+
+	require.NoError(t, runErr, input)
+	assertSameObject(t, expected, machine.LastPoppedStackElem(), input)
+}
+
+// assertSameObject compares results of both backends, their functions are
+// different objects, so only the types are compared for them.
+func assertSameObject(t *testing.T, expected, actual object.Object, input string) {
+	if expected == nil || actual == nil {
+		assert.Equal(t, expected, actual, input)
+		return
+	}
+
+	assert.Equal(t, expected.Type(), actual.Type(), input)
+	if expected.Type() != object.FUNCTION {
+		assert.Equal(t, expected.String(), actual.String(), input)
+	}
+}
+
+func Test_BigIntegersParity(t *testing.T) {
+	rt := &object.Runtime{Stdout: io.Discard, BigIntegers: true}
+	inputs := []string{
+		"let max = 9223372036854775807; [max + 1, -max - 2, max * max, 99999999999999999999 / 3, (max + 1) - 1 == max]",
+		"let f = fun(n) { if (n == 0) { 1 } else { n * f(n - 1) } }; f(30)",
+		"-(-9223372036854775807 - 1)",
+		"[2 ** 64, (-3) ** 41, 99999999999999999999 % 7, 99999999999999999999 <= 99999999999999999998]",
+		"[1 << 64, ~99999999999999999999, 99999999999999999999 & 0xffff, (1 << 100) >> 99]",
+		"99999999999999999999 / 0",
+		"2 ** 10000000000",
+		"1 << 9223372036854775807",
+	}
+
+	for _, input := range inputs {
+		p := parser.New(lexer.New(input), parser.WithBigIntegers())
+		program, err := p.Parse()
+		require.NoError(t, err, input)
+
+		c := compiler.New()
+		require.NoError(t, c.Compile(program))
+		machine := New(c.Bytecode(), WithRuntime(rt))
+		runErr := machine.Run()
+
+		expected := evaluator.Eval(program, object.NewEnvironmentWithRuntime(rt))
+		if expectedErr, ok := expected.(*object.Error); ok {
+			require.Error(t, runErr, input)
+			assert.Equal(t, expectedErr.Message, runErr.Error(), input)
+			continue
+		}
+
+		require.NoError(t, runErr, input)
+		assert.Equal(t, expected.String(), machine.LastPoppedStackElem().String(), input)
+	}
+}
+
+const fibonacci = `
+let fibonacci = fun(x) {
+    if (x < 2) {
+        return x;
+    }
+    fibonacci(x - 1) + fibonacci(x - 2)
+};
+fibonacci(25);
+`
+
+func Benchmark_Fibonacci(b *testing.B) {
+	program := parseInput(b, fibonacci)
+
+	b.Run("evaluator", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			evaluator.Eval(program, object.NewEnvironment())
+		}
+	})
+
+	b.Run("vm", func(b *testing.B) {
+		c := compiler.New()
+		require.NoError(b, c.Compile(program))
+		bytecode := c.Bytecode()
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			machine := New(bytecode)
+			require.NoError(b, machine.Run())
+		}
+	})
+}